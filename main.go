@@ -2,181 +2,373 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/baseline"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/checks"
 	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
-	"github.com/dsecuredcom/dynamic-file-searcher/pkg/domain"
-	"github.com/dsecuredcom/dynamic-file-searcher/pkg/fasthttp"
-	"github.com/dsecuredcom/dynamic-file-searcher/pkg/http"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/distributed"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/hosts"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/issues"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/output"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/plugins"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/request"
 	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
-	"github.com/dsecuredcom/dynamic-file-searcher/pkg/utils"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/scanner"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/validate"
 	"github.com/fatih/color"
 	"golang.org/x/time/rate"
 	"math/rand"
 	"os"
-	"sync"
+	"os/signal"
+	"runtime/debug"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
-const (
-	urlBufferSize = 15000
-)
-
 func main() {
-	var markers []string
+	os.Exit(run())
+}
 
+// run scans with the flags and returns the exit code, the deferred cleanups run before the exit
+func run() int {
 	cfg := config.ParseFlags()
 
-	initialDomains := domain.GetDomains(cfg.DomainsFile, cfg.Domain)
-	paths := utils.ReadLines(cfg.PathsFile)
-	if cfg.MarkersFile != "" {
-		markers = utils.ReadLines(cfg.MarkersFile)
+	if cfg.ValidateOnly {
+		if validate.Run(cfg) > 0 {
+			return config.ExitInputError
+		}
+		return config.ExitNoFindings
 	}
 
-	limiter := rate.NewLimiter(rate.Limit(cfg.Concurrency), 1)
+	if cfg.MemoryLimit > 0 {
+		debug.SetMemoryLimit(cfg.MemoryLimit)
+	}
+	debug.SetGCPercent(cfg.GCPercent)
 
-	validateInput(initialDomains, paths, markers)
+	if cfg.Mode == config.ModeAgent {
+		runAgent(cfg)
+		return config.ExitNoFindings
+	}
 
-	rand.Seed(time.Now().UnixNano())
+	if cfg.OutputFormat != "" {
+		if err := result.SetOutputFormat(cfg.OutputFormat); err != nil {
+			color.Red("[✘] Error: Invalid -output-format: %v", err)
+			return config.ExitInputError
+		}
+		// Only the rendered findings go to stdout, so it can be piped into other tools
+		color.Output = os.Stderr
+	}
 
-	printInitialInfo(cfg, initialDomains, paths)
+	if cfg.DedupDB != "" {
+		responseDB, err := result.OpenResponseDB(cfg.DedupDB)
+		if err != nil {
+			color.Red("[✘] Error: Could not open %s: %v", cfg.DedupDB, err)
+			return config.ExitInputError
+		}
+		defer responseDB.Close()
+	}
 
-	urlChan := make(chan string, urlBufferSize)
-	resultsChan := make(chan result.Result, cfg.Concurrency)
+	s := scanner.New(cfg)
+	for _, path := range cfg.AnalyzerPlugins {
+		analyzer, err := plugins.LoadAnalyzer(path)
+		if err != nil {
+			color.Red("[✘] Error: Could not load analyzer plugin %s: %v", path, err)
+			return config.ExitInputError
+		}
+		s.AddAnalyzer(analyzer)
+	}
 
-	var client interface {
-		MakeRequest(url string) result.Result
+	if cfg.Estimate {
+		if err := s.Estimate(); err != nil {
+			color.Red("[✘] Error: %v", err)
+			return config.ExitInputError
+		}
+		return config.ExitNoFindings
 	}
 
-	if cfg.FastHTTP {
-		client = fasthttp.NewClient(cfg)
-	} else {
-		client = http.NewClient(cfg)
+	rand.Seed(time.Now().UnixNano())
+
+	s.Progress = true
+	s.Controls = true
+
+	var nucleiExporter *output.NucleiExporter
+	if cfg.ExportNucleiDir != "" {
+		var err error
+		nucleiExporter, err = output.NewNucleiExporter(cfg.ExportNucleiDir, cfg.ExtraHeaders, cfg.MarkersIgnoreCase)
+		if err != nil {
+			color.Red("[✘] Error: Could not create %s: %v", cfg.ExportNucleiDir, err)
+			return config.ExitInputError
+		}
 	}
 
-	var processedCount int64
-	var totalURLs int64
+	var defectDojoExporter *output.DefectDojoExporter
+	if cfg.ExportDefectDojoFile != "" {
+		defectDojoExporter = output.NewDefectDojoExporter(cfg.ExportDefectDojoFile)
+	}
+
+	var screenshotter *output.Screenshotter
+	if cfg.ScreenshotDir != "" {
+		var err error
+		screenshotter, err = output.NewScreenshotter(cfg.ScreenshotDir, cfg.ScreenshotBrowser, cfg.ScreenshotWorkers, cfg.ScreenshotTimeout, cfg.ProxyURL, cfg.ScreenshotNoSandbox, cfg.Verbose)
+		if err != nil {
+			color.Red("[✘] Error: Could not set up screenshots: %v", err)
+			return config.ExitInputError
+		}
+	}
 
-	go generateURLs(initialDomains, paths, cfg, urlChan, &totalURLs)
+	var syslogWriter *output.SyslogWriter
+	if cfg.SyslogAddr != "" {
+		var err error
+		syslogWriter, err = output.NewSyslogWriter(cfg.SyslogAddr)
+		if err != nil {
+			color.Red("[✘] Error: Could not connect to syslog server %s: %v", cfg.SyslogAddr, err)
+			return config.ExitInputError
+		}
+		defer syslogWriter.Close()
+	}
 
-	var wg sync.WaitGroup
-	for i := 0; i < cfg.Concurrency; i++ {
-		wg.Add(1)
-		go worker(urlChan, resultsChan, &wg, client, &processedCount, limiter)
+	var kafkaPublisher *output.KafkaPublisher
+	if len(cfg.KafkaBrokers) > 0 {
+		var err error
+		kafkaPublisher, err = output.NewKafkaPublisher(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.KafkaTLS, cfg.KafkaSASL, cfg.KafkaUser, cfg.KafkaPassword)
+		if err != nil {
+			color.Red("[✘] Error: Could not connect to Kafka: %v", err)
+			return config.ExitInputError
+		}
+		defer kafkaPublisher.Close()
 	}
 
-	done := make(chan bool)
-	go trackProgress(&processedCount, &totalURLs, done)
+	var githubIssues, jiraIssues *issues.Reporter
+	if cfg.GitHubIssuesRepo != "" {
+		githubIssues = issues.NewGitHubReporter(cfg.GitHubIssuesRepo, cfg.GitHubToken, cfg.IssueLabels, cfg.Verbose)
+	}
+	if cfg.JiraProject != "" {
+		jiraIssues = issues.NewJiraReporter(cfg.JiraURL, cfg.JiraProject, cfg.JiraIssueType, cfg.JiraUser, cfg.JiraToken, cfg.IssueLabels, cfg.Verbose)
+	}
 
+	// The first interrupt stops the scan gracefully, a second one exits immediately
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		wg.Wait()
-		close(resultsChan)
-		done <- true
+		<-interrupts
+		signal.Stop(interrupts)
+		color.Yellow("\n[!] Interrupted, stopping the scan. Interrupt again to exit immediately")
+		cancel()
 	}()
 
-	for res := range resultsChan {
-		result.ProcessResult(res, cfg, markers)
+	var monitor *output.Monitor
+	if cfg.Monitor {
+		monitor = output.NewMonitor()
+		s.OnUnchanged = monitor.Unchanged
+		s.Known = monitor.Known
 	}
 
-	color.Green("\n[✔] Scan completed.")
-}
+	var findings int64
+	for {
+		started := time.Now()
+		if monitor != nil {
+			color.Cyan("\n[i] Monitoring iteration %d started", monitor.Start())
+		}
 
-func validateInput(initialDomains, paths, markers []string) {
-	if len(initialDomains) == 0 {
-		color.Red("[✘] Error: The domain list is empty. Please provide at least one domain.")
-		os.Exit(1)
-	}
+		summary := output.NewSummary()
+		s.OnWildcardHost = summary.MarkSuspect
+		findings = 0
+		err := s.Run(ctx, func(finding scanner.Finding) {
+			findings++
+			summary.Add(finding)
+			// Known findings of earlier -monitor iterations are not alerted again
+			alert := monitor == nil || monitor.Add(finding)
+			if nucleiExporter != nil {
+				if err := nucleiExporter.Add(finding); err != nil {
+					color.Red("[✘] Error: Could not export nuclei template for %s: %v", finding.URL, err)
+				}
+			}
+			if defectDojoExporter != nil {
+				defectDojoExporter.Add(finding)
+			}
+			if screenshotter != nil {
+				screenshotter.Add(finding.URL)
+			}
+			if !alert {
+				return
+			}
+			if syslogWriter != nil {
+				if err := syslogWriter.Write(finding); err != nil {
+					color.Red("[✘] Error: Could not send %s to syslog: %v", finding.URL, err)
+				}
+			}
+			if kafkaPublisher != nil {
+				if err := kafkaPublisher.Publish(finding); err != nil {
+					color.Red("[✘] Error: Could not publish %s to Kafka: %v", finding.URL, err)
+				}
+			}
+			githubIssues.Report(finding)
+			jiraIssues.Report(finding)
+		})
+		if err != nil {
+			color.Red("[✘] Error: %v", err)
+			return config.ExitInputError
+		}
+
+		summary.Print()
+
+		if monitor == nil {
+			break
+		}
+		monitor.Finish(s.Aborted())
+		if s.Aborted() {
+			break
+		}
+		// Every iteration reports its findings again, the comparison with the previous one shows
+		// what changed
+		result.ResetDuplicates()
 
-	if len(paths) == 0 {
-		color.Red("[✘] Error: The path list is empty. Please provide at least one path.")
-		os.Exit(1)
+		next := started.Add(cfg.MonitorInterval)
+		color.Cyan("[i] Next scan at %s", next.Format("2006-01-02 15:04:05"))
+		wait := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+		case <-wait.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
 	}
 
-	if len(markers) == 0 {
-		color.Yellow("[!] Warning: The marker list is empty. The scan will just use the size filter which might not be very useful.")
+	if nucleiExporter != nil {
+		if err := nucleiExporter.Close(); err != nil {
+			color.Red("[✘] Error: Could not write nuclei targets: %v", err)
+		} else {
+			color.Cyan("\n[i] Nuclei targets and templates written to %s", cfg.ExportNucleiDir)
+		}
 	}
-}
 
-func printInitialInfo(cfg config.Config, initialDomains, paths []string) {
+	if defectDojoExporter != nil {
+		if err := defectDojoExporter.Close(); err != nil {
+			color.Red("[✘] Error: Could not write %s: %v", cfg.ExportDefectDojoFile, err)
+		} else {
+			color.Cyan("\n[i] DefectDojo findings written to %s", cfg.ExportDefectDojoFile)
+		}
+	}
 
-	color.Cyan("[i] Scanning %d domains with %d paths", len(initialDomains), len(paths))
-	color.Cyan("[i] Minimum file size to detect: %d bytes", cfg.MinContentSize)
-	color.Cyan("[i] Filtering for HTTP status code: %s", cfg.HTTPStatusCodes)
+	githubIssues.Close()
+	jiraIssues.Close()
 
-	if len(cfg.ExtraHeaders) > 0 {
-		color.Cyan("[i] Using extra headers:")
-		for key, value := range cfg.ExtraHeaders {
-			color.Cyan("  %s: %s", key, value)
+	if screenshotter != nil {
+		color.Cyan("\n[i] Waiting for the remaining screenshots")
+		if dropped := screenshotter.Close(); dropped > 0 {
+			color.Yellow("[!] %d screenshots were skipped because the browser could not keep up", dropped)
 		}
+		color.Cyan("[i] Screenshots written to %s", cfg.ScreenshotDir)
+	}
+
+	if s.Aborted() {
+		color.Yellow("\n[!] Scan aborted.")
+		return config.ExitAborted
 	}
+	color.Green("\n[✔] Scan completed.")
+	if findings > 0 {
+		return config.ExitFindings
+	}
+	return config.ExitNoFindings
 }
 
-func generateURLs(initialDomains, paths []string, cfg config.Config, urlChan chan<- string, totalURLs *int64) {
-	defer close(urlChan)
+// runAgent executes URL batches pulled from a controller in serve mode with the local client settings
+func runAgent(cfg config.Config) {
+	// The requests of the checks the controller generates URLs for
+	var overrides *request.Overrides
+	if len(cfg.Checks) > 0 {
+		set, err := checks.New(cfg.Checks)
+		if err != nil {
+			color.Red("[✘] Error: %v", err)
+			os.Exit(config.ExitInputError)
+		}
+		overrides = set.Overrides()
+	}
+	if len(cfg.APITemplates) > 0 {
+		if overrides == nil {
+			overrides = request.NewOverrides()
+		}
+		overrides.AddTemplates(cfg.APITemplates)
+	}
+	client := scanner.NewClient(cfg, nil, nil, overrides)
+	limiter := rate.NewLimiter(rate.Limit(cfg.Concurrency), 1)
 
-	for _, d := range initialDomains {
-		domainURLs, _ := domain.GenerateURLs([]string{d}, paths, &cfg)
-		atomic.AddInt64(totalURLs, int64(len(domainURLs)))
-		for _, url := range domainURLs {
-			urlChan <- url
+	var rateGroups *hosts.RateGroups
+	if cfg.RateGroupsFile != "" {
+		var err error
+		rateGroups, err = hosts.NewRateGroups(cfg.RateGroupsFile)
+		if err != nil {
+			color.Red("[✘] Error: Could not read rate groups: %v", err)
+			os.Exit(config.ExitInputError)
 		}
 	}
-}
 
-func worker(urls <-chan string, results chan<- result.Result, wg *sync.WaitGroup, client interface {
-	MakeRequest(url string) result.Result
-}, processedCount *int64, limiter *rate.Limiter) {
-	defer wg.Done()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	for url := range urls {
-		err := limiter.Wait(context.Background())
-		if err != nil {
-			continue
+	// Calibration and root requests are rate limited like the batches
+	limited := scanner.NewLimitedClient(ctx, client, nil, rateGroups, limiter)
+
+	var calibrator *baseline.Calibrator
+	if cfg.Calibrate {
+		calibrator = baseline.NewCalibrator(limited, cfg.CalibrationRequests)
+		calibrator.SetNormalization(cfg.NormalizeRegexes)
+	}
+
+	var rootDiffer *baseline.RootDiffer
+	if cfg.BaselineDiff {
+		rootDiffer = baseline.NewRootDiffer(limited, cfg.BaselineSimilarity)
+	}
+
+	if cfg.MaxRuntime > 0 {
+		deadline := time.AfterFunc(cfg.MaxRuntime, cancel)
+		defer deadline.Stop()
+	}
+
+	if cfg.Proxies != nil {
+		if cfg.Proxies.Check() == 0 {
+			color.Red("[✘] Error: All %d proxies of -proxy/-proxy-file are down, none accepted a connection", cfg.Proxies.Len())
+			os.Exit(config.ExitInputError)
 		}
-		res := client.MakeRequest(url)
-		atomic.AddInt64(processedCount, 1)
-		results <- res
+		go cfg.Proxies.Run(ctx, cfg.ProxyCheckInterval, func() {
+			color.Red("\n[✘] Error: All %d proxies are down, stopping the agent", cfg.Proxies.Len())
+			cancel()
+		})
 	}
-}
 
-func trackProgress(processedCount, totalURLs *int64, done chan bool) {
-	start := time.Now()
-	lastProcessed := int64(0)
-	lastUpdate := start
+	color.Cyan("[i] Pulling batches of %d URLs from %s", cfg.BatchSize, cfg.ControllerURL)
 
-	for {
-		select {
-		case <-done:
-			return
-		default:
-			now := time.Now()
-			elapsed := now.Sub(start)
-			currentProcessed := atomic.LoadInt64(processedCount)
-			total := atomic.LoadInt64(totalURLs)
-
-			// Calculate RPS
-			intervalElapsed := now.Sub(lastUpdate)
-			intervalProcessed := currentProcessed - lastProcessed
-			rps := float64(intervalProcessed) / intervalElapsed.Seconds()
-
-			if total > 0 {
-				percentage := float64(currentProcessed) / float64(total) * 100
-				estimatedTotal := float64(elapsed) / (float64(currentProcessed) / float64(total))
-				remainingTime := time.Duration(estimatedTotal - float64(elapsed))
-				fmt.Printf("\r%-100s", "")
-				fmt.Printf("\rProgress: %.2f%% (%d/%d) | RPS: %.2f | Elapsed: %s | ETA: %s",
-					percentage, currentProcessed, total, rps,
-					elapsed.Round(time.Second), remainingTime.Round(time.Second))
-			} else {
-				fmt.Printf("\r%-100s", "")
-				fmt.Printf("\rProcessed: %d | RPS: %.2f | Elapsed: %s",
-					currentProcessed, rps, elapsed.Round(time.Second))
-			}
+	var processedCount int64
+	agent := distributed.NewAgent(cfg.ControllerURL, cfg.Token, cfg.BatchSize, cfg.Concurrency, cfg.Verbose)
+	err := agent.Run(ctx, func(url string) result.Result {
+		if err := rateGroups.Wait(ctx, url); err != nil {
+			return result.Result{URL: url, Error: err}
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			return result.Result{URL: url, Error: err}
+		}
+		res := client.MakeRequest(url)
+		atomic.AddInt64(&processedCount, 1)
 
-			lastProcessed = currentProcessed
-			lastUpdate = now
+		if calibrator != nil && res.Error == nil {
+			res.SoftNotFound = calibrator.IsSoftNotFound(res)
+		}
 
-			time.Sleep(time.Second)
+		if rootDiffer != nil && res.Error == nil && !res.SoftNotFound {
+			res.DiffersFromBaseline = rootDiffer.Differs(res)
 		}
+
+		return res
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		color.Red("[✘] Error: %v", err)
+		os.Exit(config.ExitInputError)
 	}
+
+	color.Green("\n[✔] Agent finished, processed %d URLs.", processedCount)
 }
@@ -0,0 +1,116 @@
+package ahocorasick
+
+// Automaton finds any of a set of patterns in a byte stream in a single pass. The stream can be
+// fed in arbitrary chunks, the matching state is carried over by the caller.
+type Automaton struct {
+	next       []map[byte]int32
+	fail       []int32
+	output     []int32
+	ignoreCase bool
+}
+
+// Build creates an automaton for patterns. Empty patterns are ignored. With ignoreCase, ASCII
+// letters of the patterns and the stream are compared case-insensitively.
+func Build(patterns []string, ignoreCase bool) *Automaton {
+	a := &Automaton{ignoreCase: ignoreCase}
+	a.addState()
+
+	for index, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+
+		state := int32(0)
+		for i := 0; i < len(pattern); i++ {
+			b := a.normalize(pattern[i])
+			next, exists := a.next[state][b]
+			if !exists {
+				next = a.addState()
+				a.next[state][b] = next
+			}
+			state = next
+		}
+		if a.output[state] < 0 {
+			a.output[state] = int32(index)
+		}
+	}
+
+	a.buildFailureLinks()
+	return a
+}
+
+func (a *Automaton) addState() int32 {
+	a.next = append(a.next, make(map[byte]int32))
+	a.fail = append(a.fail, 0)
+	a.output = append(a.output, -1)
+	return int32(len(a.next) - 1)
+}
+
+func (a *Automaton) buildFailureLinks() {
+	queue := make([]int32, 0, len(a.next))
+	for _, child := range a.next[0] {
+		queue = append(queue, child)
+	}
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		for b, child := range a.next[state] {
+			queue = append(queue, child)
+
+			fail := a.fail[state]
+			for {
+				if next, exists := a.next[fail][b]; exists {
+					a.fail[child] = next
+					break
+				}
+				if fail == 0 {
+					break
+				}
+				fail = a.fail[fail]
+			}
+
+			// A state also reports the patterns ending in its longest proper suffix
+			if a.output[child] < 0 {
+				a.output[child] = a.output[a.fail[child]]
+			}
+		}
+	}
+}
+
+func (a *Automaton) normalize(b byte) byte {
+	if a.ignoreCase && b >= 'A' && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// Empty reports whether the automaton contains no pattern
+func (a *Automaton) Empty() bool {
+	return len(a.next[0]) == 0
+}
+
+// Scan feeds chunk starting in state. It returns the state to continue with and, for the first
+// pattern found, its index and the position in chunk right after it (-1 if nothing was found).
+func (a *Automaton) Scan(state int32, chunk []byte) (int32, int, int) {
+	for i := 0; i < len(chunk); i++ {
+		b := a.normalize(chunk[i])
+		for {
+			if next, exists := a.next[state][b]; exists {
+				state = next
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = a.fail[state]
+		}
+
+		if match := a.output[state]; match >= 0 {
+			return state, int(match), i + 1
+		}
+	}
+
+	return state, -1, -1
+}
@@ -0,0 +1,186 @@
+package baseline
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+type Client interface {
+	MakeRequest(url string) result.Result
+}
+
+// Paths used to provoke the "not found" behaviour of a host. %s is replaced by a random token.
+var calibrationTemplates = []string{"%s", "%s.html", "%s/%s", "%s.json", ".%s"}
+
+const tokenAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// calibrationSimilarity is the similarity a body of the same size as a not-found page needs to be
+// suppressed although its hash differs, e.g. because of a request id. Real files which only happen
+// to have the size of the not-found page are reported.
+const calibrationSimilarity = 0.8
+
+type signature struct {
+	statusCode  int
+	contentType string
+	fileSize    int64
+	bodyHash    uint64
+	// body is the normalized body, tokens are only kept for the recorded not-found signatures
+	body   string
+	tokens map[string]int
+}
+
+type hostCalibration struct {
+	once       sync.Once
+	signatures []signature
+}
+
+// Calibrator learns how every host answers requests for non-existent paths and
+// reports responses that look exactly like that (wildcard / soft-404 pages).
+type Calibrator struct {
+	client   Client
+	requests int
+	// normalizers strip dynamic parts of the bodies before they are compared
+	normalizers []*regexp.Regexp
+
+	mu    sync.Mutex
+	hosts map[string]*hostCalibration
+}
+
+func NewCalibrator(client Client, requests int) *Calibrator {
+	if requests < 1 {
+		requests = 1
+	}
+	return &Calibrator{
+		client:   client,
+		requests: requests,
+		hosts:    make(map[string]*hostCalibration),
+	}
+}
+
+// SetNormalization strips the matches of regexes from bodies before their signature is computed
+func (c *Calibrator) SetNormalization(regexes []*regexp.Regexp) {
+	c.normalizers = regexes
+}
+
+// IsSoftNotFound calibrates the host of res on first use and returns true if res matches
+// one of the recorded not-found signatures.
+func (c *Calibrator) IsSoftNotFound(res result.Result) bool {
+	base, path, ok := splitURL(res.URL)
+	if !ok {
+		return false
+	}
+
+	hc := c.getHost(base)
+	hc.once.Do(func() {
+		hc.signatures = c.calibrate(base)
+	})
+
+	sig := c.newSignature(res, path)
+	for _, known := range hc.signatures {
+		if known.matches(sig) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Calibrator) getHost(base string) *hostCalibration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hc, exists := c.hosts[base]
+	if !exists {
+		hc = &hostCalibration{}
+		c.hosts[base] = hc
+	}
+	return hc
+}
+
+func (c *Calibrator) calibrate(base string) []signature {
+	var signatures []signature
+
+	for i := 0; i < c.requests; i++ {
+		template := calibrationTemplates[i%len(calibrationTemplates)]
+		path := strings.ReplaceAll(template, "%s", randomToken(12))
+
+		res := c.client.MakeRequest(fmt.Sprintf("%s/%s", base, path))
+		if res.Error != nil {
+			continue
+		}
+
+		sig := c.newSignature(res, "/"+path)
+		sig.tokens = tokenize(sig.body)
+		sig.body = ""
+		signatures = append(signatures, sig)
+	}
+
+	return signatures
+}
+
+func (s signature) matches(other signature) bool {
+	if s.statusCode != other.statusCode || s.contentType != other.contentType {
+		return false
+	}
+	if s.bodyHash == other.bodyHash {
+		return true
+	}
+	return s.fileSize == other.fileSize && similarity(s.tokens, tokenize(other.body)) >= calibrationSimilarity
+}
+
+func (c *Calibrator) newSignature(res result.Result, path string) signature {
+	body := normalizeBody(result.NormalizeBody(res.Content, c.normalizers), path)
+	return signature{
+		statusCode:  res.StatusCode,
+		contentType: strings.ToLower(res.ContentType),
+		fileSize:    res.FileSize,
+		bodyHash:    hashString(body),
+		body:        body,
+	}
+}
+
+// normalizeBody strips reflections of the requested path, soft-404 pages frequently echo it back.
+func normalizeBody(content, path string) string {
+	if path == "" || path == "/" {
+		return content
+	}
+
+	content = strings.ReplaceAll(content, path, "")
+
+	if idx := strings.LastIndex(path, "/"); idx >= 0 && idx < len(path)-1 {
+		content = strings.ReplaceAll(content, path[idx+1:], "")
+	}
+
+	return content
+}
+
+func splitURL(rawURL string) (string, string, bool) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.Host == "" {
+		return "", "", false
+	}
+	return parsedURL.Scheme + "://" + parsedURL.Host, parsedURL.Path, true
+}
+
+func randomToken(length int) string {
+	token := make([]byte, length)
+	for i := range token {
+		token[i] = tokenAlphabet[rand.Intn(len(tokenAlphabet))]
+	}
+	return string(token)
+}
+
+func hashString(s string) uint64 {
+	h := uint64(14695981039346656037) // FNV offset basis
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211 // FNV prime
+	}
+	return h
+}
@@ -0,0 +1,118 @@
+package baseline
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+type rootBaseline struct {
+	once    sync.Once
+	fetched bool
+	root    result.Result
+	tokens  map[string]int
+}
+
+// RootDiffer fetches the root page of every host once and reports generated paths
+// whose responses substantially differ from it.
+type RootDiffer struct {
+	client     Client
+	similarity float64
+
+	mu    sync.Mutex
+	hosts map[string]*rootBaseline
+}
+
+func NewRootDiffer(client Client, similarity float64) *RootDiffer {
+	return &RootDiffer{
+		client:     client,
+		similarity: similarity,
+		hosts:      make(map[string]*rootBaseline),
+	}
+}
+
+// Differs returns true if res has a different status code or content type than the host root,
+// or if its body is less similar to the root body than the configured threshold.
+func (d *RootDiffer) Differs(res result.Result) bool {
+	base, _, ok := splitURL(res.URL)
+	if !ok {
+		return false
+	}
+
+	rb := d.getHost(base)
+	rb.once.Do(func() {
+		root := d.client.MakeRequest(base + "/")
+		if root.Error != nil {
+			return
+		}
+		rb.root = root
+		rb.tokens = tokenize(root.Content)
+		rb.fetched = true
+	})
+
+	if !rb.fetched {
+		// Without a baseline everything would differ, which is not useful
+		return false
+	}
+
+	if res.StatusCode != rb.root.StatusCode {
+		return true
+	}
+
+	if !strings.EqualFold(mediaType(res.ContentType), mediaType(rb.root.ContentType)) {
+		return true
+	}
+
+	return similarity(rb.tokens, tokenize(res.Content)) < d.similarity
+}
+
+func (d *RootDiffer) getHost(base string) *rootBaseline {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	rb, exists := d.hosts[base]
+	if !exists {
+		rb = &rootBaseline{}
+		d.hosts[base] = rb
+	}
+	return rb
+}
+
+func tokenize(content string) map[string]int {
+	tokens := make(map[string]int)
+	for _, token := range strings.Fields(content) {
+		tokens[token]++
+	}
+	return tokens
+}
+
+// similarity is the Dice coefficient of both token multisets (1 = identical, 0 = nothing in common).
+func similarity(a, b map[string]int) float64 {
+	totalA, totalB, common := 0, 0, 0
+
+	for token, countA := range a {
+		totalA += countA
+		if countB, exists := b[token]; exists {
+			if countA < countB {
+				common += countA
+			} else {
+				common += countB
+			}
+		}
+	}
+
+	for _, countB := range b {
+		totalB += countB
+	}
+
+	if totalA+totalB == 0 {
+		return 1
+	}
+
+	return 2 * float64(common) / float64(totalA+totalB)
+}
+
+func mediaType(contentType string) string {
+	return strings.TrimSpace(strings.Split(contentType, ";")[0])
+}
@@ -0,0 +1,67 @@
+package bloom
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// Filter is a fixed-size bloom filter. Its memory usage only depends on the expected number of
+// items and the false positive rate, not on the number of items added.
+type Filter struct {
+	mu     sync.Mutex
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+// New sizes the filter for expectedItems with the given false positive rate
+func New(expectedItems uint64, falsePositiveRate float64) *Filter {
+	if expectedItems == 0 {
+		expectedItems = 1
+	}
+
+	size := uint64(math.Ceil(-float64(expectedItems) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint64(math.Round(float64(size) / float64(expectedItems) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+
+	return &Filter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// AddIfNew adds s and reports whether it was not contained before. With the configured
+// probability a new item is wrongly reported as already contained.
+func (f *Filter) AddIfNew(s string) bool {
+	a := fnv.New64a()
+	a.Write([]byte(s))
+	h1 := a.Sum64()
+	b := fnv.New64()
+	b.Write([]byte(s))
+	// Odd, so the probe sequence of the double hashing never gets stuck
+	h2 := b.Sum64() | 1
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	isNew := false
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.size
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if f.bits[word]&mask == 0 {
+			isNew = true
+			f.bits[word] |= mask
+		}
+	}
+
+	return isNew
+}
+
+// SizeBytes returns the memory used by the bit array
+func (f *Filter) SizeBytes() int {
+	return len(f.bits) * 8
+}
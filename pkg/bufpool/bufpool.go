@@ -0,0 +1,28 @@
+package bufpool
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Buffers which grew beyond this are dropped instead of being kept alive by the pool
+const maxPooledCapacity = 1 << 20
+
+var pool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// Get returns an empty buffer. It must be handed back with Put once its content was copied.
+func Get() *bytes.Buffer {
+	return pool.Get().(*bytes.Buffer)
+}
+
+func Put(buffer *bytes.Buffer) {
+	if buffer.Cap() > maxPooledCapacity {
+		return
+	}
+	buffer.Reset()
+	pool.Put(buffer)
+}
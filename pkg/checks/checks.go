@@ -0,0 +1,97 @@
+// Package checks contains built-in sets of paths whose responses are validated by their content, so
+// well-known exposures are found without own paths and markers.
+package checks
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/request"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+// check requests path and reports the response if its content matches signature. If request is
+// set it is sent instead of a GET.
+type check struct {
+	path      string
+	signature *regexp.Regexp
+	detection string
+	request   *request.Spec
+}
+
+var sets = map[string][]check{}
+
+// Set holds the checks of the selected check sets
+type Set struct {
+	checks []check
+}
+
+func New(names []string) (*Set, error) {
+	s := &Set{}
+	for _, name := range names {
+		checks, ok := sets[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown check set '%s'", name)
+		}
+		s.checks = append(s.checks, checks...)
+	}
+	return s, nil
+}
+
+// Paths returns the paths of all checks
+func (s *Set) Paths() []string {
+	paths := make([]string, 0, len(s.checks))
+	for _, c := range s.checks {
+		paths = append(paths, c.path)
+	}
+	return paths
+}
+
+// Overrides returns the requests of the checks which don't use a GET, nil if there are none
+func (s *Set) Overrides() *request.Overrides {
+	var overrides *request.Overrides
+	for _, c := range s.checks {
+		if c.request == nil {
+			continue
+		}
+		if overrides == nil {
+			overrides = request.NewOverrides()
+		}
+		overrides.AddPath(c.path, *c.request)
+	}
+	return overrides
+}
+
+// Analyzer returns a result.Analyzer which reports the responses of check paths with the expected
+// content and rejects all others, e.g. soft-404 pages answering /.git/HEAD
+func (s *Set) Analyzer() result.Analyzer {
+	return analyzer{checks: s.checks}
+}
+
+type analyzer struct {
+	checks []check
+}
+
+func (a analyzer) Name() string {
+	return "checks"
+}
+
+func (a analyzer) Analyze(res result.Result) result.Analysis {
+	parsed, err := url.Parse(res.URL)
+	if err != nil {
+		return result.Analysis{}
+	}
+
+	for _, c := range a.checks {
+		if !strings.HasSuffix(parsed.Path, "/"+c.path) {
+			continue
+		}
+		if c.signature.MatchString(res.Content) {
+			return result.Analysis{Verdict: result.VerdictMatch, Detection: c.detection}
+		}
+		return result.Analysis{Verdict: result.VerdictReject}
+	}
+	return result.Analysis{}
+}
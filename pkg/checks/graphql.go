@@ -0,0 +1,40 @@
+package checks
+
+import (
+	"regexp"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/request"
+)
+
+// A minimal introspection query, servers with disabled introspection answer it with an error
+var introspectionQuery = request.Spec{
+	Method:  "POST",
+	Headers: map[string]string{"Content-Type": "application/json"},
+	Body:    []byte(`{"query":"query IntrospectionQuery{__schema{queryType{name}}}"}`),
+}
+
+var graphQLPaths = []string{
+	"graphql",
+	"api/graphql",
+	"v1/graphql",
+	"v2/graphql",
+	"graphql/v1",
+	"gql",
+	"graphql.php",
+}
+
+// The schema only shows up in the data of a successful introspection
+var introspectionSignature = regexp.MustCompile(`"__schema"\s*:\s*\{\s*"queryType"`)
+
+func init() {
+	var endpoints []check
+	for _, path := range graphQLPaths {
+		endpoints = append(endpoints, check{
+			path:      path,
+			signature: introspectionSignature,
+			detection: "graphql-introspection",
+			request:   &introspectionQuery,
+		})
+	}
+	sets["graphql"] = endpoints
+}
@@ -0,0 +1,28 @@
+package checks
+
+import "regexp"
+
+// OpenAPIPaths are the usual locations of Swagger and OpenAPI specifications
+var OpenAPIPaths = []string{
+	"swagger.json",
+	"swagger.yaml",
+	"openapi.json",
+	"openapi.yaml",
+	"api-docs",
+	"v2/api-docs",
+	"v3/api-docs",
+	"swagger/v1/swagger.json",
+	"api/swagger.json",
+	"api/openapi.json",
+}
+
+// openAPISignature matches the version field of JSON and YAML specifications
+var openAPISignature = regexp.MustCompile(`("(swagger|openapi)"\s*:\s*"[23]\.|(?m)^(swagger|openapi):\s*['"]?[23]\.)`)
+
+func init() {
+	var specs []check
+	for _, path := range OpenAPIPaths {
+		specs = append(specs, check{path: path, signature: openAPISignature, detection: "openapi-spec"})
+	}
+	sets["openapi"] = specs
+}
@@ -0,0 +1,16 @@
+package checks
+
+import "regexp"
+
+func init() {
+	sets["vcs"] = []check{
+		{path: ".git/HEAD", signature: regexp.MustCompile(`^(ref: refs/|[0-9a-f]{40}\s*$)`), detection: "git-head"},
+		{path: ".git/config", signature: regexp.MustCompile(`(?m)^\[core\]`), detection: "git-config"},
+		{path: ".svn/entries", signature: regexp.MustCompile(`^(\d+\s*\n|<\?xml[\s\S]*<wc-entries)`), detection: "svn-entries"},
+		{path: ".svn/wc.db", signature: regexp.MustCompile(`^SQLite format 3\x00`), detection: "svn-wc-db"},
+		{path: ".hg/requires", signature: regexp.MustCompile(`(?m)^(revlogv1|store|fncache|dotencode)$`), detection: "hg-requires"},
+		{path: ".bzr/README", signature: regexp.MustCompile(`This is a Bazaar control directory`), detection: "bzr-readme"},
+		// Bud1 is the magic of the buddy allocator file format of .DS_Store
+		{path: ".DS_Store", signature: regexp.MustCompile(`^\x00\x00\x00\x01Bud1`), detection: "ds-store"},
+	}
+}
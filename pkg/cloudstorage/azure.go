@@ -0,0 +1,33 @@
+package cloudstorage
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+// Storage account names are 3 to 24 lowercase letters and digits
+var azureAccountRegex = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+
+func init() {
+	providers["azure"] = provider{
+		name:      "azure",
+		validName: azureAccountRegex.MatchString,
+		url: func(bucket string) string {
+			// Unknown accounts don't resolve, existing ones answer the container listing with an error or the list
+			return "https://" + bucket + ".blob.core.windows.net/?comp=list"
+		},
+		owns: func(host string) bool {
+			return strings.HasSuffix(host, ".blob.core.windows.net")
+		},
+		signatures: []signature{
+			{pattern: "<EnumerationResults", verdict: result.VerdictMatch, detection: "public-listing"},
+			{pattern: "<Code>AccountIsDisabled</Code>", verdict: result.VerdictMatch, detection: "exists-account-disabled"},
+			{pattern: "<Code>NoAuthenticationInformation</Code>", verdict: result.VerdictMatch, detection: "exists-access-denied"},
+			{pattern: "<Code>AuthenticationFailed</Code>", verdict: result.VerdictMatch, detection: "exists-access-denied"},
+			{pattern: "<Code>ResourceNotFound</Code>", verdict: result.VerdictMatch, detection: "exists"},
+			{pattern: "<Code>InvalidQueryParameterValue</Code>", verdict: result.VerdictMatch, detection: "exists"},
+		},
+	}
+}
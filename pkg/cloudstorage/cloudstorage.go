@@ -0,0 +1,127 @@
+// Package cloudstorage generates cloud storage bucket URLs from the words of a host and classifies
+// the responses of the storage providers by their error signatures.
+package cloudstorage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/domain"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/hosts"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+// signature classifies a response whose body contains pattern
+type signature struct {
+	pattern   string
+	verdict   result.Verdict
+	detection string
+}
+
+type provider struct {
+	name string
+	// validName reports whether bucket is a valid bucket name for the provider
+	validName func(bucket string) bool
+	// url returns the URL listing the bucket
+	url func(bucket string) string
+	// owns reports whether host belongs to the provider
+	owns func(host string) bool
+	// signatures are checked in order, the first hit decides
+	signatures []signature
+}
+
+var providers = map[string]provider{}
+
+// Scanner generates the bucket URLs of the selected providers and classifies their responses
+type Scanner struct {
+	providers []provider
+}
+
+func NewScanner(names []string) (*Scanner, error) {
+	s := &Scanner{}
+	for _, name := range names {
+		p, ok := providers[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown cloud storage provider '%s'", name)
+		}
+		s.providers = append(s.providers, p)
+	}
+	return s, nil
+}
+
+// URLs returns the URLs of all candidate buckets derived from host and its words
+func (s *Scanner) URLs(host string, words []string) []string {
+	var urls []string
+	for _, bucket := range BucketNames(host, words) {
+		for _, p := range s.providers {
+			if p.validName(bucket) {
+				urls = append(urls, p.url(bucket))
+			}
+		}
+	}
+	return urls
+}
+
+// BucketNames derives candidate bucket names from host, e.g. for shop.example.com and the word
+// shop: example, example.com, shop.example.com, shop, example-shop, shop-example and exampleshop
+func BucketNames(host string, words []string) []string {
+	host = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://"))
+	host = strings.Split(strings.Split(host, "/")[0], ":")[0]
+
+	apex := domain.Apex(host)
+	company := strings.Split(apex, ".")[0]
+
+	names := []string{company, apex, host}
+	for _, word := range words {
+		word = strings.ToLower(word)
+		if word == company {
+			continue
+		}
+		names = append(names, word, company+"-"+word, word+"-"+company, company+word)
+	}
+
+	seen := make(map[string]bool)
+	unique := names[:0]
+	for _, name := range names {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// Analyzer returns a result.Analyzer which reports existing buckets of the selected providers and
+// rejects the responses for buckets which don't exist
+func (s *Scanner) Analyzer() result.Analyzer {
+	return analyzer{providers: s.providers}
+}
+
+type analyzer struct {
+	providers []provider
+}
+
+func (a analyzer) Name() string {
+	return "cloud-storage"
+}
+
+func (a analyzer) Analyze(res result.Result) result.Analysis {
+	host := strings.ToLower(hosts.Host(res.URL))
+	for _, p := range a.providers {
+		if !p.owns(host) {
+			continue
+		}
+		for _, sig := range p.signatures {
+			if strings.Contains(res.Content, sig.pattern) {
+				return result.Analysis{Verdict: sig.verdict, Detection: p.name + "-" + sig.detection}
+			}
+		}
+		// Unknown answers of a provider are not worth a finding
+		return result.Analysis{Verdict: result.VerdictReject}
+	}
+	return result.Analysis{}
+}
+
+// dnsBucketRegex matches bucket names which are valid as a DNS label sequence
+var dnsBucketRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
@@ -0,0 +1,34 @@
+package cloudstorage
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+// GCS additionally allows underscores but no names starting with goog or containing google
+var gcsBucketRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,61}[a-z0-9]$`)
+
+func init() {
+	providers["gcs"] = provider{
+		name: "gcs",
+		validName: func(bucket string) bool {
+			return gcsBucketRegex.MatchString(bucket) && !strings.Contains(bucket, "..") &&
+				!strings.HasPrefix(bucket, "goog") && !strings.Contains(bucket, "google")
+		},
+		url: func(bucket string) string {
+			return "https://storage.googleapis.com/" + bucket + "/"
+		},
+		owns: func(host string) bool {
+			return host == "storage.googleapis.com"
+		},
+		signatures: []signature{
+			{pattern: "<ListBucketResult", verdict: result.VerdictMatch, detection: "public-listing"},
+			{pattern: "<Code>NoSuchBucket</Code>", verdict: result.VerdictReject},
+			{pattern: "<Code>AccessDenied</Code>", verdict: result.VerdictMatch, detection: "exists-access-denied"},
+			{pattern: "<Code>UserProjectAccountProblem</Code>", verdict: result.VerdictMatch, detection: "exists-billing-disabled"},
+			{pattern: "<Code>UserProjectMissing</Code>", verdict: result.VerdictMatch, detection: "exists-requester-pays"},
+		},
+	}
+}
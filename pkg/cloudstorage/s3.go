@@ -0,0 +1,33 @@
+package cloudstorage
+
+import (
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+func init() {
+	providers["s3"] = provider{
+		name: "s3",
+		validName: func(bucket string) bool {
+			return dnsBucketRegex.MatchString(bucket) && !strings.Contains(bucket, "..")
+		},
+		url: func(bucket string) string {
+			// Dotted bucket names break the wildcard certificate of the virtual-hosted style
+			if strings.Contains(bucket, ".") {
+				return "https://s3.amazonaws.com/" + bucket + "/"
+			}
+			return "https://" + bucket + ".s3.amazonaws.com/"
+		},
+		owns: func(host string) bool {
+			return host == "s3.amazonaws.com" || strings.HasSuffix(host, ".s3.amazonaws.com")
+		},
+		signatures: []signature{
+			{pattern: "<ListBucketResult", verdict: result.VerdictMatch, detection: "public-listing"},
+			{pattern: "<Code>NoSuchBucket</Code>", verdict: result.VerdictReject},
+			{pattern: "<Code>AccessDenied</Code>", verdict: result.VerdictMatch, detection: "exists-access-denied"},
+			{pattern: "<Code>AllAccessDisabled</Code>", verdict: result.VerdictMatch, detection: "exists-access-disabled"},
+			{pattern: "<Code>PermanentRedirect</Code>", verdict: result.VerdictMatch, detection: "exists-other-region"},
+		},
+	}
+}
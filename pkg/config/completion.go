@@ -0,0 +1,162 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const binaryName = "dynamic_file_searcher"
+
+// Flags whose value is a path, shells complete file names for them
+var fileFlags = map[string]bool{
+	"domains":            true,
+	"burp":               true,
+	"input-httpx":        true,
+	"domain-tags":        true,
+	"exclude-domains":    true,
+	"paths":              true,
+	"priority-paths":     true,
+	"paths-map":          true,
+	"resume-file":        true,
+	"proxy-file":         true,
+	"previous-store":     true,
+	"wayback-cache":      true,
+	"markers":            true,
+	"base-paths":         true,
+	"api-templates":      true,
+	"rate-groups":        true,
+	"store-all":          true,
+	"export-nuclei":      true,
+	"export-defectdojo":  true,
+	"screenshots":        true,
+	"screenshot-browser": true,
+	"spill-dir":          true,
+	"dedup-db":           true,
+	"status-socket":      true,
+	"config":             true,
+	"analyzer-plugin":    true,
+}
+
+type completionFlag struct {
+	name   string
+	usage  string
+	isBool bool
+	isFile bool
+}
+
+// completionScript generates a completion script for all registered flags
+func completionScript(shell string) (string, error) {
+	var flags []completionFlag
+	flag.VisitAll(func(f *flag.Flag) {
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			name:   f.Name,
+			usage:  f.Usage,
+			isBool: ok && boolFlag.IsBoolFlag(),
+			isFile: fileFlags[f.Name],
+		})
+	})
+	sort.Slice(flags, func(i, j int) bool { return flags[i].name < flags[j].name })
+
+	switch shell {
+	case "bash":
+		return bashCompletion(flags), nil
+	case "zsh":
+		return zshCompletion(flags), nil
+	case "fish":
+		return fishCompletion(flags), nil
+	default:
+		return "", fmt.Errorf("unsupported shell '%s' (use bash, zsh or fish)", shell)
+	}
+}
+
+func bashCompletion(flags []completionFlag) string {
+	var all, files, values []string
+	for _, f := range flags {
+		all = append(all, "-"+f.name)
+		if f.isFile {
+			files = append(files, "-"+f.name)
+		} else if !f.isBool {
+			values = append(values, "-"+f.name)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "_%s() {\n", binaryName)
+	b.WriteString("    local cur prev\n")
+	b.WriteString("    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n\n")
+	b.WriteString("    if [[ ${COMP_CWORD} -eq 1 && \"${cur}\" != -* ]]; then\n")
+	b.WriteString("        COMPREPLY=( $(compgen -W \"completion serve agent\" -- \"${cur}\") )\n")
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n\n")
+	b.WriteString("    if [[ \"${prev}\" == \"completion\" ]]; then\n")
+	b.WriteString("        COMPREPLY=( $(compgen -W \"bash zsh fish\" -- \"${cur}\") )\n")
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n\n")
+	b.WriteString("    case \"${prev}\" in\n")
+	fmt.Fprintf(&b, "        %s)\n", strings.Join(files, "|"))
+	b.WriteString("            COMPREPLY=( $(compgen -f -- \"${cur}\") )\n")
+	b.WriteString("            return\n")
+	b.WriteString("            ;;\n")
+	fmt.Fprintf(&b, "        %s)\n", strings.Join(values, "|"))
+	b.WriteString("            return\n")
+	b.WriteString("            ;;\n")
+	b.WriteString("    esac\n\n")
+	fmt.Fprintf(&b, "    COMPREPLY=( $(compgen -W \"%s\" -- \"${cur}\") )\n", strings.Join(all, " "))
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "complete -o default -F _%s %s\n", binaryName, binaryName)
+
+	return b.String()
+}
+
+func zshCompletion(flags []completionFlag) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n\n", binaryName)
+	fmt.Fprintf(&b, "_%s() {\n", binaryName)
+	b.WriteString("    _arguments \\\n")
+	for _, f := range flags {
+		usage := zshEscape(f.usage)
+		switch {
+		case f.isBool:
+			fmt.Fprintf(&b, "        '-%s[%s]' \\\n", f.name, usage)
+		case f.isFile:
+			fmt.Fprintf(&b, "        '-%s[%s]:file:_files' \\\n", f.name, usage)
+		default:
+			fmt.Fprintf(&b, "        '-%s[%s]:value: ' \\\n", f.name, usage)
+		}
+	}
+	b.WriteString("        '1::command:(completion serve agent)'\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "_%s \"$@\"\n", binaryName)
+
+	return b.String()
+}
+
+func fishCompletion(flags []completionFlag) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "complete -c %s -n '__fish_use_subcommand' -a completion -d 'Generate shell completion'\n", binaryName)
+	fmt.Fprintf(&b, "complete -c %s -n '__fish_use_subcommand' -a serve -d 'Hand out URLs to agents'\n", binaryName)
+	fmt.Fprintf(&b, "complete -c %s -n '__fish_use_subcommand' -a agent -d 'Scan URLs pulled from a controller'\n", binaryName)
+	fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'\n", binaryName)
+	for _, f := range flags {
+		usage := strings.ReplaceAll(f.usage, "'", "\\'")
+		switch {
+		case f.isBool:
+			fmt.Fprintf(&b, "complete -c %s -o %s -d '%s'\n", binaryName, f.name, usage)
+		case f.isFile:
+			fmt.Fprintf(&b, "complete -c %s -o %s -d '%s' -r -F\n", binaryName, f.name, usage)
+		default:
+			fmt.Fprintf(&b, "complete -c %s -o %s -d '%s' -x\n", binaryName, f.name, usage)
+		}
+	}
+
+	return b.String()
+}
+
+func zshEscape(s string) string {
+	replacer := strings.NewReplacer("'", "'\\''", "[", "\\[", "]", "\\]", ":", "\\:")
+	return replacer.Replace(s)
+}
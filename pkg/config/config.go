@@ -4,27 +4,76 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"net"
+	"net/textproto"
 	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/doh"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/expr"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/importer"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/proxy"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/request"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/shard"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/statuscode"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/utils"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/version"
 )
 
 var defaultAppendEnvList = []string{"prod", "dev", "test"}
 
+// Exit codes of the scanner, so CI jobs and wrapper scripts can branch on the outcome
+const (
+	ExitNoFindings = 0
+	ExitFindings   = 1
+	// ExitInputError is also the exit code of invalid flags
+	ExitInputError = 2
+	// ExitAborted means the scan was interrupted or reached -max-runtime or -max-total-bytes before all
+	// URLs were processed
+	ExitAborted = 3
+)
+
+const (
+	ModeServe = "serve"
+	ModeAgent = "agent"
+)
+
 type Config struct {
 	DomainsFile              string
 	Domain                   string
-	PathsFile                string
-	MarkersFile              string
+	BurpFile                 string
+	BurpDirs                 bool
+	InputHttpxFile           string
+	ImportedTargets          []string
+	HostWords                map[string][]string
+	PathsFiles               []string
+	PriorityPathsFiles       []string
+	Wayback                  bool
+	CloudStorage             []string
+	Checks                   []string
+	WaybackLimit             int
+	WaybackCacheDir          string
+	JSDiscovery              bool
+	JSDepth                  int
+	OpenAPI                  bool
+	Paths                    []string
+	MarkersFiles             []string
+	Markers                  []string
 	BasePathsFile            string
 	Concurrency              int
 	Timeout                  time.Duration
+	AdaptiveTimeout          bool
+	AdaptiveTimeoutMax       time.Duration
 	Verbose                  bool
 	ProxyURL                 *url.URL
 	ExtraHeaders             map[string]string
 	FastHTTP                 bool
 	ForceHTTPProt            bool
+	Matrix                   []Target
 	HostDepth                int
 	AppendByPassesToWords    bool
 	SkipRootFolderCheck      bool
@@ -34,6 +83,7 @@ type Config struct {
 	EnvRemoving              bool
 	MinContentSize           int64
 	MaxContentRead           int64
+	ContentReadLimits        map[string]int64
 	HTTPStatusCodes          string
 	ContentTypes             string
 	DisallowedContentTypes   string
@@ -41,67 +91,730 @@ type Config struct {
 	EnvAppendWords           string
 	AppendEnvList            []string
 	DisableDuplicateCheck    bool
+	Calibrate                bool
+	CalibrationRequests      int
+	BaselineDiff             bool
+	BaselineSimilarity       float64
+	DedupBy                  string
+	DedupHeader              string
+	DedupDB                  string
+	TitleRegex               *regexp.Regexp
+	Favicon                  bool
+	MatchFaviconHashes       []string
+	FilterFaviconHashes      []string
+	MatchServers             []string
+	FilterServers            []string
+	WAFDetect                bool
+	WAFRate                  float64
+	TLSInfo                  bool
+	DetectTypes              string
+	DetectSecrets            bool
+	EntropyThreshold         float64
+	EntropyMinLength         int
+	MarkersIgnoreCase        bool
+	MarkersReloadInterval    time.Duration
+	ContextBytes             int
+	StoreAllFile             string
+	ExportNucleiDir          string
+	ExportDefectDojoFile     string
+	ScreenshotDir            string
+	ScreenshotBrowser        string
+	ScreenshotWorkers        int
+	ScreenshotTimeout        time.Duration
+	ScreenshotNoSandbox      bool
+	GitHubIssuesRepo         string
+	GitHubToken              string
+	JiraURL                  string
+	JiraProject              string
+	JiraIssueType            string
+	JiraUser                 string
+	JiraToken                string
+	IssueLabels              []string
+	SyslogAddr               string
+	KafkaBrokers             []string
+	KafkaTopic               string
+	KafkaTLS                 bool
+	KafkaSASL                string
+	KafkaUser                string
+	KafkaPassword            string
+	StatusMatcher            *statuscode.Matcher
+	StopHostOnMatch          bool
+	MaxMatchesPerHost        int
+	MaxMatchesSkipRequests   bool
+	MemoryLimit              int64
+	GCPercent                int
+	AdmissionThreshold       float64
+	AdmissionShrinkReads     bool
+	ValidateOnly             bool
+	DomainTagsFile           string
+	PathsMapFile             string
+	StatusSocket             string
+	ExcludeDomainsFile       string
+	ExcludeRegex             *regexp.Regexp
+	Randomize                bool
+	RandomizeWindow          int
+	MaxFindings              int
+	MaxRuntime               time.Duration
+	Estimate                 bool
+	Autoscale                bool
+	Mode                     string
+	ListenAddr               string
+	ControllerURL            string
+	Token                    string
+	BatchSize                int
+	LeaseTimeout             time.Duration
+	RedisURL                 string
+	RedisPrefix              string
+	RedisIdleTimeout         time.Duration
+	Shard                    *shard.Shard
+	OnMatchExec              string
+	OnMatchExecParallel      int
+	OnMatchExecTimeout       time.Duration
+	AnalyzerPlugins          []string
+	URLDedupCapacity         uint64
+	FairHosts                int
+	SpillDir                 string
+	URLBuffer                int
+	ResultBuffer             int
+	ReadBuffer               int
+	ResponseCache            int
+	APIMode                  bool
+	APITemplatesFile         string
+	APITemplates             []string
+	Verify                   bool
+	VerifyDelay              time.Duration
+	RateGroupsFile           string
+	WildcardRatio            float64
+	WildcardMinRequests      int
+	NormalizeBody            bool
+	NormalizeRegexes         []*regexp.Regexp
+	MatchExpr                *expr.Expr
+	WWWVariants              bool
+	MinTransferRate          int64
+	CrossProtocolDedup       bool
+	DNSWildcard              bool
+	Personas                 []request.Persona
+	OutputFormat             string
+	Unique                   bool
+	UniqueCapacity           uint64
+	MaxTotalBytes            int64
+	ResumeFile               string
+	ProxyFile                string
+	ProxyCheckInterval       time.Duration
+	// Proxies rotates the requests over -proxy and the proxies of -proxy-file, nil without proxy
+	Proxies *proxy.Pool
+	SNI     string
+	DoH     string
+	// Resolver resolves the hosts with -doh, nil for the system resolver
+	Resolver          *doh.Resolver
+	PreviousStoreFile string
+	// Conditions are the validators of the URLs of -previous-store, nil without it
+	Conditions      *request.Conditions
+	Monitor         bool
+	MonitorInterval time.Duration
 }
 
 func ParseFlags() Config {
 	cfg := Config{
 		ExtraHeaders: make(map[string]string),
 	}
-	flag.StringVar(&cfg.DomainsFile, "domains", "", "File containing list of domains")
+	flag.StringVar(&cfg.DomainsFile, "domains", "", "File containing list of domains (use - to stream domains from stdin)")
 	flag.StringVar(&cfg.Domain, "domain", "", "Single domain to scan")
-	flag.StringVar(&cfg.PathsFile, "paths", "", "File containing list of paths")
-	flag.StringVar(&cfg.MarkersFile, "markers", "", "File containing list of markers")
+	flag.StringVar(&cfg.BurpFile, "burp", "", "Burp Suite site map (XML) or target scope (JSON) export whose hosts are scanned")
+	flag.BoolVar(&cfg.BurpDirs, "burp-dirs", false, "Use the directories observed in the -burp site map as additional base paths")
+	flag.StringVar(&cfg.InputHttpxFile, "input-httpx", "", "httpx -json output whose URLs are scanned with the found scheme and port, page title words extend the path generation")
+	flag.StringVar(&cfg.DomainTagsFile, "domain-tags", "", "File with lines like 'example.com,program-a;owner-b' whose tags are attached to the results of the host, in addition to tags in the -domains lines")
+	flag.StringVar(&cfg.ExcludeDomainsFile, "exclude-domains", "", "File containing hosts to drop from the input (one per line, *.example.com excludes all subdomains)")
+
+	var excludeRegexStr string
+	flag.StringVar(&excludeRegexStr, "exclude-regex", "", "Drop hosts matching this regular expression from the input")
+
+	flag.Func("paths", "File containing list of paths (csv allowed, may be repeated to merge several wordlists)", func(value string) error {
+		cfg.PathsFiles = append(cfg.PathsFiles, splitCSV(value)...)
+		return nil
+	})
+	flag.StringVar(&cfg.PathsMapFile, "paths-map", "", "File mapping host patterns to tailored paths files, one '<pattern> <paths file>' per line (e.g. '*.wordpress-host.com wp-paths.txt')")
+	flag.Func("priority-paths", "File containing high-value paths which are requested on all hosts before the other paths (csv allowed, may be repeated)", func(value string) error {
+		cfg.PriorityPathsFiles = append(cfg.PriorityPathsFiles, splitCSV(value)...)
+		return nil
+	})
+	flag.Func("cloud-storage", "Scan for cloud storage buckets named after the words of each host instead of paths (csv allowed, supported: s3, azure, gcs)", func(value string) error {
+		cfg.CloudStorage = append(cfg.CloudStorage, splitCSV(strings.ToLower(value))...)
+		return nil
+	})
+	flag.BoolVar(&cfg.JSDiscovery, "js-discovery", false, "Add the paths referenced by the JavaScript files of each host's root page")
+	flag.IntVar(&cfg.JSDepth, "js-depth", 2, "How many levels of script references -js-discovery follows from the root page")
+	flag.BoolVar(&cfg.OpenAPI, "openapi", false, "Probe each host for a Swagger/OpenAPI specification and add the paths it documents")
+	flag.Func("checks", "Built-in check sets whose paths are requested first and validated by their content (csv allowed, supported: vcs, openapi, graphql)", func(value string) error {
+		cfg.Checks = append(cfg.Checks, splitCSV(strings.ToLower(value))...)
+		return nil
+	})
+	flag.BoolVar(&cfg.Wayback, "wayback", false, "Add the paths the Wayback Machine archived for the apex domain of each host")
+	flag.IntVar(&cfg.WaybackLimit, "wayback-limit", 1000, "Maximum number of Wayback Machine paths per apex domain")
+	flag.StringVar(&cfg.WaybackCacheDir, "wayback-cache", "", "Directory to cache the Wayback Machine paths per apex domain for a week")
+	flag.Func("markers", "File containing list of markers (csv allowed, may be repeated to merge several marker files)", func(value string) error {
+		cfg.MarkersFiles = append(cfg.MarkersFiles, splitCSV(value)...)
+		return nil
+	})
+	flag.Func("marker", "Single marker to search for (csv allowed, may be repeated). regex: and jsonpath: markers are never split", func(value string) error {
+		if strings.HasPrefix(value, "regex:") || strings.HasPrefix(value, "jsonpath:") {
+			cfg.Markers = append(cfg.Markers, value)
+			return nil
+		}
+		cfg.Markers = append(cfg.Markers, splitCSV(value)...)
+		return nil
+	})
+	flag.Func("analyzer-plugin", "Go plugin (.so) exporting an Analyzer variable that implements result.Analyzer (csv allowed, may be repeated)", func(value string) error {
+		cfg.AnalyzerPlugins = append(cfg.AnalyzerPlugins, splitCSV(value)...)
+		return nil
+	})
+	flag.BoolVar(&cfg.NormalizeBody, "normalize-body", false, "Strip timestamps, CSRF tokens, nonces, session IDs and UUIDs from bodies before they are hashed for the duplicate check and soft-404 calibration")
+	flag.Func("normalize-regex", "Additional regular expression stripped from bodies before hashing, implies -normalize-body (may be repeated)", func(value string) error {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return err
+		}
+		cfg.NormalizeRegexes = append(cfg.NormalizeRegexes, re)
+		return nil
+	})
+	flag.BoolVar(&cfg.MarkersIgnoreCase, "markers-ignore-case", false, "Match markers case-insensitively")
+	flag.DurationVar(&cfg.MarkersReloadInterval, "markers-reload-interval", 0, "Check the markers file for changes in this interval and reload it mid-scan (e.g. 1m, 0 = disabled)")
+	flag.IntVar(&cfg.ContextBytes, "context-bytes", 75, "Number of body bytes printed before and after a matched marker")
+	flag.Func("path", "Single path to check, may be repeated (e.g. -path /backup.zip -path .env)", func(value string) error {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "/")
+		if value != "" {
+			cfg.Paths = append(cfg.Paths, value)
+		}
+		return nil
+	})
 	flag.StringVar(&cfg.BasePathsFile, "base-paths", "", "File containing list of base paths")
 	flag.IntVar(&cfg.Concurrency, "concurrency", 10, "Number of concurrent requests")
+	flag.BoolVar(&cfg.Autoscale, "autoscale", false, "Adjust the number of active workers (up to -concurrency) based on error rate, latency and memory usage")
 	flag.IntVar(&cfg.HostDepth, "host-depth", 6, "How many sub-subdomains to use for path generation (e.g., 2 = test1-abc & test2 [based on test1-abc.test2.test3.example.com])")
 	flag.BoolVar(&cfg.DontGeneratePaths, "dont-generate-paths", false, "If true, only the base paths (or nothing) will be used for scanning")
+	flag.BoolVar(&cfg.Randomize, "randomize", false, "Shuffle generated URLs within a sliding window to spread the load across hosts")
+	flag.IntVar(&cfg.RandomizeWindow, "randomize-window", 100000, "Number of URLs held in memory for -randomize")
+	flag.IntVar(&cfg.FairHosts, "fair-hosts", 50, "Number of hosts whose URLs are requested round-robin, so one big host cannot occupy all workers (1 = one host after another)")
+	flag.StringVar(&cfg.SpillDir, "spill-dir", "", "Buffer generated URLs the workers cannot take yet in a temporary file in this directory instead of pausing the generation")
+	flag.IntVar(&cfg.URLBuffer, "url-buffer", 15000, "Number of generated URLs queued for the workers")
+	flag.IntVar(&cfg.ResultBuffer, "result-buffer", 0, "Number of responses queued for the result processing (0 = -concurrency)")
+	flag.IntVar(&cfg.ResponseCache, "response-cache", 0, "Number of responses cached so URLs which only differ in host case, default port or fragment are requested once (0 = disabled)")
+	var readBuffer string
+	flag.StringVar(&readBuffer, "read-buffer", "32KB", "Size of the chunks response bodies are read in while the markers are searched (e.g. 64KB)")
+	flag.Uint64Var(&cfg.URLDedupCapacity, "url-dedup-capacity", 10000000, "Expected number of URLs for the bloom filter that drops duplicate generated URLs, sets its memory usage (~2.4MB per million, 0 = disabled)")
+
+	flag.BoolVar(&cfg.WAFDetect, "waf-detect", false, "Detect WAF block and challenge pages (Cloudflare, Akamai, Imperva, ...), drop them and slow down hosts which returned several")
+	flag.Float64Var(&cfg.WildcardRatio, "wildcard-ratio", 0, "Stop scanning hosts which answer at least this fraction of requests with a 200 of at least -min-content-size, their earlier findings are suspect (0 = disabled)")
+	flag.IntVar(&cfg.WildcardMinRequests, "wildcard-min-requests", 25, "Number of responses of a host before -wildcard-ratio is applied")
+	flag.StringVar(&cfg.RateGroupsFile, "rate-groups", "", "File limiting the requests per second of host patterns, one '<pattern> <rps>' per line (e.g. '*.fragile-partner.com 1')")
+	flag.Float64Var(&cfg.WAFRate, "waf-rate", 1, "Requests per second to a host shielded by a WAF with -waf-detect (0 = skip its remaining URLs)")
+
+	var shardStr string
+	flag.StringVar(&shardStr, "shard", "", "Only scan the URLs of this shard (e.g. 2/5), run the other shards with the same input on other machines")
 	flag.DurationVar(&cfg.Timeout, "timeout", 12*time.Second, "Timeout for each request")
+	flag.BoolVar(&cfg.AdaptiveTimeout, "adaptive-timeout", false, "Adapt the timeout per host to its response times, -timeout is used until a host answered a few requests")
+	flag.DurationVar(&cfg.AdaptiveTimeoutMax, "adaptive-timeout-max", 30*time.Second, "Upper bound of the per-host timeout of slow hosts with -adaptive-timeout")
+	var minTransferRate string
+	flag.StringVar(&minTransferRate, "min-transfer-rate", "0", "Abort response bodies which are read slower than this many bytes per second after their first 2 seconds, e.g. 1KB, so tarpits cannot hold workers until the timeout (0 = disabled)")
 	flag.BoolVar(&cfg.Verbose, "verbose", false, "Verbose output")
+	flag.StringVar(&cfg.StatusSocket, "status-socket", "", "Serve a JSON snapshot of the scan progress to every connection of this unix socket (also written to stderr on SIGUSR1)")
 	flag.BoolVar(&cfg.SkipRootFolderCheck, "skip-root-folder-check", false, "Prevents checking https://domain/PATH")
 	flag.BoolVar(&cfg.AppendByPassesToWords, "append-bypasses-to-words", false, "Append bypasses to words (admin -> admin; -> admin..;)")
 	flag.BoolVar(&cfg.FastHTTP, "use-fasthttp", false, "Use fasthttp instead of net/http")
 	flag.BoolVar(&cfg.ForceHTTPProt, "force-http", false, "Force the usage of http:// instead of https://")
+	flag.BoolVar(&cfg.DNSWildcard, "dns-wildcard", false, "Resolve a random label of the parent domain of every host and skip hosts resolving to the same addresses, e.g. subdomains of a catch-all DNS zone")
+	flag.BoolVar(&cfg.WWWVariants, "www-variants", false, "Also scan the www./non-www. counterpart of every host, unless it does not resolve or resolves to the same addresses")
+	var matrix string
+	flag.StringVar(&matrix, "matrix", "", "Scheme and port combinations every host is expanded into (e.g. 'http:80,8080;https:443,8443', default https:443 or http:80 with -force-http)")
+	flag.BoolVar(&cfg.Verify, "verify", false, "Request every match a second time (without Range) and only report it if the match reproduces")
+	flag.DurationVar(&cfg.VerifyDelay, "verify-delay", time.Second, "Delay before the verification request of -verify")
+	flag.BoolVar(&cfg.APIMode, "api-mode", false, "Also POST JSON bodies to generated API-looking paths (/api/, /v1/, .json, ...)")
+	flag.StringVar(&cfg.APITemplatesFile, "api-templates", "", "File with one JSON body template per line for -api-mode, {{word}}, {{host}} and {{path}} are replaced (default {})")
 	flag.BoolVar(&cfg.NoEnvAppending, "dont-append-envs", false, "Prevent appending environment variables to requests (-qa, ...)")
 	flag.BoolVar(&cfg.EnvRemoving, "remove-envs", true, "In case a word ends with a known envword, a variant without the envword will be added")
 	flag.StringVar(&cfg.ContentTypes, "content-types", "", "Content-Type header values to filter (csv allowed, e.g. json,octet)")
 	flag.StringVar(&cfg.DisallowedContentStrings, "disallowed-content-strings", "", "If this string is present in the response body, the request will be considered as inrelevant (csv allowed, e.g. '<html>,<body>'")
 	flag.StringVar(&cfg.DisallowedContentTypes, "disallowed-content-types", "", "Content-Type header value to filter out (csv allowed, e.g. json,octet)")
+	flag.StringVar(&cfg.DetectTypes, "detect-types", "", "File types detected by magic bytes to filter, independent of Content-Type (csv allowed, e.g. zip,gzip,tar,sqlite,sql,pe,pdf)")
+	flag.BoolVar(&cfg.DetectSecrets, "detect-secrets", false, "Report responses containing high-entropy tokens (likely keys/credentials) even if no marker matches")
+	flag.Float64Var(&cfg.EntropyThreshold, "entropy-threshold", 4.5, "Minimum Shannon entropy (bits per character) for a token to be considered a secret")
+	flag.IntVar(&cfg.EntropyMinLength, "entropy-min-length", 20, "Minimum length of a token to be checked for entropy")
 	flag.Int64Var(&cfg.MinContentSize, "min-content-size", 0, "Minimum file size to detect (in bytes)")
 	flag.Int64Var(&cfg.MaxContentRead, "max-content-read", 5*1024*1024, "Maximum size of content to read for marker checking (in bytes)")
-	flag.StringVar(&cfg.HTTPStatusCodes, "http-statuses", "", "HTTP status code to filter (csv allowed)")
+	flag.Func("content-read-limits", "Read limits per content type instead of -max-content-read (csv allowed, e.g. text/html=4KB,application/octet-stream=1MB)", func(value string) error {
+		if cfg.ContentReadLimits == nil {
+			cfg.ContentReadLimits = make(map[string]int64)
+		}
+		for _, mapping := range splitCSV(value) {
+			contentType, size, found := strings.Cut(mapping, "=")
+			if !found {
+				return fmt.Errorf("invalid mapping '%s', it must be content-type=size", mapping)
+			}
+			limit, err := parseByteSize(size)
+			if err != nil {
+				return err
+			}
+			cfg.ContentReadLimits[strings.ToLower(strings.TrimSpace(contentType))] = limit
+		}
+		return nil
+	})
+	flag.StringVar(&cfg.HTTPStatusCodes, "http-statuses", "", "HTTP status code to filter (csv allowed, supports classes, ranges and negation, e.g. 2xx,300-302,!204)")
+	flag.StringVar(&cfg.OutputFormat, "output-format", "", "Print every finding as a line of this text/template to stdout instead of the colored report, which goes to stderr with the progress (e.g. '{{.Status}} {{.Size}} {{.URL}} {{.Marker}}')")
+	flag.BoolVar(&cfg.Unique, "unique", false, "Never print the same URL twice, even if it matches again through another generation route")
+	flag.Uint64Var(&cfg.UniqueCapacity, "unique-capacity", 1000000, "Expected number of findings for the bounded set of printed URLs of -unique, sets its memory usage (~3.6MB per million)")
+	flag.StringVar(&cfg.PreviousStoreFile, "previous-store", "", "-store-all file of an earlier scan, URLs with an ETag or Last-Modified in it are requested conditionally and skipped if they are unchanged (304)")
+	flag.StringVar(&cfg.StoreAllFile, "store-all", "", "Write the metadata of every response (url, status, size, content type, duration) to this JSONL file, regardless of a match")
+	flag.StringVar(&cfg.ExportDefectDojoFile, "export-defectdojo", "", "Write the findings in the DefectDojo generic findings JSON format to this file")
+	flag.StringVar(&cfg.ScreenshotDir, "screenshots", "", "Capture a screenshot of every finding with headless Chrome into this directory")
+	flag.StringVar(&cfg.ScreenshotBrowser, "screenshot-browser", "", "Chrome or Chromium binary for -screenshots (default: looked up in PATH)")
+	flag.IntVar(&cfg.ScreenshotWorkers, "screenshot-workers", 2, "Number of headless browsers capturing -screenshots at the same time")
+	flag.DurationVar(&cfg.ScreenshotTimeout, "screenshot-timeout", 20*time.Second, "Maximum time to load and capture a single screenshot")
+	flag.BoolVar(&cfg.ScreenshotNoSandbox, "screenshot-no-sandbox", false, "Start the browsers of -screenshots without the Chrome sandbox, which Chrome requires when running as root, e.g. in containers")
+	flag.StringVar(&cfg.GitHubIssuesRepo, "github-issues", "", "Open a GitHub issue per finding in this repository (owner/name, token from GITHUB_TOKEN)")
+	flag.StringVar(&cfg.JiraProject, "jira-issues", "", "Open a Jira issue per finding in this project key (credentials from JIRA_USER and JIRA_TOKEN)")
+	flag.StringVar(&cfg.JiraURL, "jira-url", "", "Base URL of the Jira instance for -jira-issues (e.g. https://example.atlassian.net)")
+	flag.StringVar(&cfg.JiraIssueType, "jira-issue-type", "Bug", "Issue type of the -jira-issues")
+	flag.Func("issue-labels", "Labels added to the issues of -github-issues and -jira-issues (csv allowed)", func(value string) error {
+		cfg.IssueLabels = append(cfg.IssueLabels, splitCSV(value)...)
+		return nil
+	})
+	flag.StringVar(&cfg.SyslogAddr, "syslog", "", "Send every finding as an RFC 5424 message to this syslog server (host:port, udp://host:port or tcp://host:port)")
+	flag.Func("kafka-brokers", "Kafka bootstrap brokers to publish every finding as JSON record to -kafka-topic (csv allowed, host:port)", func(value string) error {
+		cfg.KafkaBrokers = append(cfg.KafkaBrokers, splitCSV(value)...)
+		return nil
+	})
+	flag.StringVar(&cfg.KafkaTopic, "kafka-topic", "dfs-findings", "Kafka topic of the findings published to -kafka-brokers")
+	flag.BoolVar(&cfg.KafkaTLS, "kafka-tls", false, "Connect to -kafka-brokers with TLS")
+	flag.StringVar(&cfg.KafkaSASL, "kafka-sasl", "", "SASL mechanism for -kafka-brokers: plain, scram-sha-256 or scram-sha-512, the credentials are read from KAFKA_USER and KAFKA_PASSWORD")
+	flag.StringVar(&cfg.ExportNucleiDir, "export-nuclei", "", "Write a nuclei template per finding and a target list to this directory to re-verify the findings with nuclei")
+	flag.BoolVar(&cfg.StopHostOnMatch, "stop-host-on-match", false, "Discard the remaining URLs of a host once it yielded a match")
+	flag.IntVar(&cfg.MaxMatchesPerHost, "max-matches-per-host", 0, "Mute further findings for a host after this many matches (0 = unlimited)")
+	flag.BoolVar(&cfg.MaxMatchesSkipRequests, "max-matches-skip-requests", false, "Also stop requesting a host once it reached -max-matches-per-host")
+	flag.IntVar(&cfg.MaxFindings, "max-findings", 0, "Stop the scan gracefully after this many findings (0 = unlimited)")
+	flag.BoolVar(&cfg.Monitor, "monitor", false, "Keep running and repeat the scan every -interval, unchanged URLs are requested conditionally and the new and gone findings of every iteration are listed")
+	flag.DurationVar(&cfg.MonitorInterval, "interval", 24*time.Hour, "Time between the starts of the scans of -monitor")
+	flag.DurationVar(&cfg.MaxRuntime, "max-runtime", 0, "Stop the scan gracefully after this duration, in-flight results are still reported (e.g. 2h, 0 = unlimited)")
+	var maxTotalBytes string
+	flag.StringVar(&maxTotalBytes, "max-total-bytes", "0", "Stop the scan gracefully once the responses add up to this many bytes, e.g. 50GB for a VPS with a transfer quota (0 = unlimited)")
+	flag.StringVar(&cfg.ResumeFile, "resume-file", "", "Record the processed URLs in this file and skip the URLs recorded by earlier runs, so a stopped scan continues where it left off")
+	flag.StringVar(&cfg.OnMatchExec, "on-match-exec", "", "Run this command without a shell for every finding, {{url}}, {{host}}, {{path}} and {{detection}} are replaced within its arguments (e.g. 'curl -sO {{url}}')")
+	flag.IntVar(&cfg.OnMatchExecParallel, "on-match-exec-parallel", 4, "Maximum number of -on-match-exec commands running at the same time")
+	flag.DurationVar(&cfg.OnMatchExecTimeout, "on-match-exec-timeout", 5*time.Minute, "Kill an -on-match-exec command running longer than this, a finding is skipped if no command slot becomes free within it")
 	flag.BoolVar(&cfg.DisableDuplicateCheck, "disable-duplicate-check", false, "Disable duplicate response check by host and size")
+	flag.StringVar(&cfg.DedupBy, "dedup-by", "size", "Duplicate response check strategy: 'size' (host and size), 'hash' (SHA-256 of the body across all hosts) or 'header:<name>' (host and the value of a response header, e.g. header:ETag)")
+	flag.BoolVar(&cfg.CrossProtocolDedup, "cross-protocol-dedup", true, "Share the duplicate check of a host across http, https and all ports, so hosts mirroring their content are reported once (false = check every scheme and port on its own)")
+	flag.StringVar(&cfg.DedupDB, "dedup-db", "", "Keep the duplicate response check in this file instead of memory, so it survives restarts and its memory usage does not grow")
+	flag.BoolVar(&cfg.Calibrate, "calibrate", false, "Request random non-existent paths per host first and suppress responses matching that wildcard/soft-404 baseline")
+	flag.IntVar(&cfg.CalibrationRequests, "calibration-requests", 3, "Number of random non-existent paths requested per host for calibration")
+	flag.BoolVar(&cfg.BaselineDiff, "baseline-diff", false, "Fetch the host root once and only report responses that differ from it (status, content type or body similarity)")
+	flag.Float64Var(&cfg.BaselineSimilarity, "baseline-similarity", 0.8, "Body similarity (0-1) below which a response counts as different from the root baseline")
+
+	var titleRegexStr string
+	flag.StringVar(&titleRegexStr, "filter-title-regex", "", "Only report HTML responses whose <title> matches this regular expression (e.g. 'Index of|phpMyAdmin')")
+
+	var matchExprStr string
+	flag.StringVar(&matchExprStr, "match-expr", "", "Only report responses for which this expression holds, e.g. 'status == 200 && size > 1KB && ct contains \"octet\" && body contains \"PK\"'")
+
+	flag.BoolVar(&cfg.Favicon, "favicon", false, "Request /favicon.ico once per host and report its Shodan-style mmh3 hash with the findings")
+	flag.Func("match-favicon", "Only report findings of hosts with one of these favicon hashes (csv allowed, implies -favicon)", func(value string) error {
+		cfg.MatchFaviconHashes = append(cfg.MatchFaviconHashes, splitCSV(value)...)
+		return nil
+	})
+	flag.Func("filter-favicon", "Drop findings of hosts with one of these favicon hashes (csv allowed, implies -favicon)", func(value string) error {
+		cfg.FilterFaviconHashes = append(cfg.FilterFaviconHashes, splitCSV(value)...)
+		return nil
+	})
+	flag.StringVar(&cfg.DoH, "doh", "", "Resolve the hosts with this DNS-over-HTTPS endpoint instead of the system resolver, e.g. https://1.1.1.1/dns-query")
+	flag.StringVar(&cfg.SNI, "sni", "", "Send this server name in the TLS handshake instead of the host of the URL, e.g. the CDN hostname when scanning origin IPs")
+	flag.BoolVar(&cfg.TLSInfo, "tls-info", false, "Add subject, issuer, SANs and expiry of the TLS certificate of the host to the findings and the -store-all records")
+	flag.Func("match-server", "Only report findings whose Server or X-Powered-By header contains one of these technologies, e.g. IIS (csv allowed, case insensitive)", func(value string) error {
+		cfg.MatchServers = append(cfg.MatchServers, splitCSV(value)...)
+		return nil
+	})
+	flag.Func("filter-server", "Drop findings whose Server or X-Powered-By header contains one of these technologies, e.g. nginx (csv allowed, case insensitive)", func(value string) error {
+		cfg.FilterServers = append(cfg.FilterServers, splitCSV(value)...)
+		return nil
+	})
 
 	var proxyURLStr string
 	flag.StringVar(&proxyURLStr, "proxy", "", "Proxy URL (e.g., http://127.0.0.1:8080)")
+	flag.StringVar(&cfg.ProxyFile, "proxy-file", "", "File with one proxy URL per line, the requests rotate over the proxies which pass their health checks")
+	flag.DurationVar(&cfg.ProxyCheckInterval, "proxy-check-interval", 30*time.Second, "Health check the proxies of -proxy and -proxy-file this often, dead ones are taken out of the rotation and the scan stops if all are down")
+
+	var persona string
+	flag.StringVar(&persona, "persona", "rotate", "Browser persona whose matching User-Agent, Accept, Accept-Language and sec-ch-ua headers are sent: "+strings.Join(request.PersonaNames(), ", ")+" (csv rotates between the given ones, 'rotate' between all)")
 
 	var extraHeaders string
 	flag.StringVar(&extraHeaders, "headers", "", "Extra headers to add to each request (format: 'Header1:Value1,Header2:Value2')")
 
 	flag.StringVar(&cfg.EnvAppendWords, "env-append-words", "", "Comma-separated list of environment words to append (e.g. dev,prod,api)")
 
-	flag.Parse()
+	flag.BoolVar(&cfg.ValidateOnly, "validate", false, "Validate all input files and rules, report malformed lines and exit without scanning")
+
+	flag.BoolVar(&cfg.Estimate, "estimate", false, "Only generate the URLs without sending requests and print the expected number of URLs and scan duration")
+
+	var memoryLimit string
+	flag.StringVar(&memoryLimit, "mem-limit", "", "Soft memory limit for the Go runtime (e.g. 512MB, 1.4GB, 8GB), empty = no limit")
+	flag.Float64Var(&cfg.AdmissionThreshold, "admission-threshold", 0.85, "Pause the URL generation while the heap is above this share of -mem-limit (0 = disabled)")
+	flag.BoolVar(&cfg.AdmissionShrinkReads, "admission-shrink-reads", false, "Also read less of each response body (a quarter of -max-content-read) while the heap is above -admission-threshold")
+	flag.IntVar(&cfg.GCPercent, "gc-percent", 100, "Garbage collector target percentage (lower = less memory, more CPU)")
+
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "YAML or TOML file with flag names as keys, command-line flags override its values")
+
+	var showVersion bool
+	flag.BoolVar(&showVersion, "version", false, "Print version, commit and build date and exit")
+
+	var profile string
+	flag.StringVar(&profile, "profile", "", "Name of a profile from the 'profiles' section of the -config file")
+
+	flag.StringVar(&cfg.ListenAddr, "listen", ":9090", "Address the controller listens on for agents (serve mode)")
+	flag.StringVar(&cfg.ControllerURL, "controller", "", "URL of the controller to pull URL batches from (agent mode, e.g. http://10.0.0.1:9090)")
+	flag.StringVar(&cfg.Token, "token", "", "Shared secret agents must present to the controller (serve and agent mode)")
+	flag.IntVar(&cfg.BatchSize, "batch-size", 50, "Number of URLs an agent requests per batch (agent mode)")
+	flag.DurationVar(&cfg.LeaseTimeout, "lease-timeout", 5*time.Minute, "Hand out URLs again if they were not processed within this duration (serve mode and -redis)")
+	flag.StringVar(&cfg.RedisURL, "redis", "", "Share the URL queue and findings of several instances through Redis (e.g. redis://10.0.0.1:6379/0)")
+	flag.StringVar(&cfg.RedisPrefix, "redis-prefix", "dfs", "Prefix of the Redis keys, use a different one per scan")
+	flag.DurationVar(&cfg.RedisIdleTimeout, "redis-idle-timeout", 30*time.Second, "Time the shared Redis queue has to stay empty without producers before an instance finishes")
+
+	if len(os.Args) > 1 && os.Args[1] == "completion" {
+		if len(os.Args) != 3 {
+			fmt.Println("Usage: dynamic_file_searcher completion bash|zsh|fish")
+			os.Exit(ExitInputError)
+		}
+		script, err := completionScript(os.Args[2])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(ExitInputError)
+		}
+		fmt.Print(script)
+		os.Exit(0)
+	}
 
-	if (cfg.DomainsFile == "" && cfg.Domain == "") && cfg.PathsFile == "" {
-		fmt.Println("Please provide either -domains file or -domain, along with -paths")
+	if len(os.Args) > 1 && (os.Args[1] == ModeServe || os.Args[1] == ModeAgent) {
+		cfg.Mode = os.Args[1]
+		flag.CommandLine.Parse(os.Args[2:])
+	} else {
+		flag.Parse()
+	}
+
+	if showVersion {
+		fmt.Println(version.String())
+		os.Exit(0)
+	}
+
+	if profile != "" && configFile == "" {
+		fmt.Println("-profile requires a -config file")
+		os.Exit(ExitInputError)
+	}
+
+	if configFile != "" {
+		if err := loadConfigFile(configFile, profile); err != nil {
+			fmt.Printf("Error reading config file: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+	}
+
+	if cfg.Mode == ModeAgent && cfg.ControllerURL == "" {
+		fmt.Println("Agent mode requires -controller")
+		os.Exit(ExitInputError)
+	}
+
+	if cfg.Mode == ModeServe && cfg.Token == "" && !loopbackAddr(cfg.ListenAddr) {
+		fmt.Println("Serve mode requires -token unless -listen is a loopback address like 127.0.0.1:9090")
+		os.Exit(ExitInputError)
+	}
+
+	if cfg.Mode == ModeServe && cfg.Verify {
+		fmt.Println("-verify is not supported in serve mode, the agents do not know the markers to verify the matches with")
+		os.Exit(ExitInputError)
+	}
+
+	if cfg.Mode != ModeAgent && cfg.RedisURL == "" && !cfg.HasDomainInput() && len(cfg.CloudStorage) == 0 && len(cfg.Checks) == 0 && len(cfg.PathsFiles) == 0 && len(cfg.PriorityPathsFiles) == 0 && len(cfg.Paths) == 0 {
+		fmt.Println("Please provide either -domains file, -domain, -burp or -input-httpx, along with -paths or -path")
 		flag.PrintDefaults()
-		os.Exit(1)
+		os.Exit(ExitInputError)
+	}
+
+	if cfg.HTTPStatusCodes != "" {
+		matcher, err := statuscode.Parse(cfg.HTTPStatusCodes)
+		if err != nil {
+			fmt.Printf("Invalid -http-statuses value: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		cfg.StatusMatcher = matcher
+	}
+
+	if shardStr != "" {
+		selected, err := shard.Parse(shardStr)
+		if err != nil {
+			fmt.Printf("Invalid -shard value: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		cfg.Shard = selected
+	}
+
+	if excludeRegexStr != "" {
+		excludeRegex, err := regexp.Compile(excludeRegexStr)
+		if err != nil {
+			fmt.Printf("Invalid exclude regex: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		cfg.ExcludeRegex = excludeRegex
+	}
+
+	if titleRegexStr != "" {
+		titleRegex, err := regexp.Compile(titleRegexStr)
+		if err != nil {
+			fmt.Printf("Invalid title regex: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		cfg.TitleRegex = titleRegex
+	}
+
+	if matchExprStr != "" {
+		matchExpr, err := expr.Compile(matchExprStr)
+		if err != nil {
+			fmt.Printf("Invalid match expression: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		cfg.MatchExpr = matchExpr
+	}
+
+	for _, hash := range append(cfg.MatchFaviconHashes, cfg.FilterFaviconHashes...) {
+		if _, err := strconv.ParseInt(hash, 10, 32); err != nil {
+			fmt.Printf("Invalid favicon hash '%s', it must be a signed 32-bit integer\n", hash)
+			os.Exit(ExitInputError)
+		}
+	}
+	if len(cfg.MatchFaviconHashes) > 0 || len(cfg.FilterFaviconHashes) > 0 {
+		cfg.Favicon = true
 	}
 
-	if (cfg.DomainsFile != "" || cfg.Domain != "") && cfg.PathsFile != "" && cfg.MarkersFile == "" && noRulesSpecified(cfg) {
-		fmt.Println("If you provide -domains or -domain and -paths, you must provide at least one of -markers, -http-status, -content-types, -min-content-size, or -disallowed-content-types")
+	if cfg.HasDomainInput() && (len(cfg.PathsFiles) > 0 || len(cfg.PriorityPathsFiles) > 0 || len(cfg.Paths) > 0 || len(cfg.Checks) > 0 || cfg.PathsMapFile != "") && len(cfg.MarkersFiles) == 0 && len(cfg.Markers) == 0 && !cfg.DetectSecrets && noRulesSpecified(cfg) {
+		fmt.Println("If you provide -domains, -domain, -burp or -input-httpx and -paths or -path, you must provide at least one of -markers, -marker, -detect-secrets, -http-status, -content-types, -min-content-size, -disallowed-content-types, -baseline-diff, -filter-title-regex, -match-expr, -match-favicon, -match-server, -detect-types or -analyzer-plugin")
 		flag.PrintDefaults()
-		os.Exit(1)
+		os.Exit(ExitInputError)
+	}
+
+	if memoryLimit != "" {
+		limit, err := parseByteSize(memoryLimit)
+		if err != nil {
+			fmt.Printf("Invalid -mem-limit value: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		cfg.MemoryLimit = limit
+	}
+
+	if matrix != "" {
+		if cfg.ForceHTTPProt {
+			fmt.Println("-force-http and -matrix cannot be combined, use -matrix http:80 instead")
+			os.Exit(ExitInputError)
+		}
+		targets, err := ParseMatrix(matrix)
+		if err != nil {
+			fmt.Printf("Invalid -matrix value: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		cfg.Matrix = targets
+		// Sources probing each host once (JS crawling, OpenAPI) use the scheme of the first target
+		cfg.ForceHTTPProt = targets[0].Scheme == "http"
+	} else if cfg.ForceHTTPProt {
+		cfg.Matrix = []Target{{Scheme: "http", Port: 80}}
+	} else {
+		cfg.Matrix = []Target{{Scheme: "https", Port: 443}}
+	}
+
+	size, err := parseByteSize(readBuffer)
+	if err != nil || size < 1024 || size > 64*1024*1024 {
+		fmt.Println("Invalid -read-buffer value, it must be between 1KB and 64MB")
+		os.Exit(ExitInputError)
+	}
+	cfg.ReadBuffer = int(size)
+
+	if minTransferRate != "0" {
+		if cfg.MinTransferRate, err = parseByteSize(minTransferRate); err != nil {
+			fmt.Println("Invalid -min-transfer-rate value, it must be a size like 512 or 1KB")
+			os.Exit(ExitInputError)
+		}
+	}
+
+	if maxTotalBytes != "0" {
+		if cfg.MaxTotalBytes, err = parseByteSize(maxTotalBytes); err != nil {
+			fmt.Println("Invalid -max-total-bytes value, it must be a size like 500MB or 50GB")
+			os.Exit(ExitInputError)
+		}
+	}
+
+	personas, err := request.ParsePersonas(persona)
+	if err != nil {
+		fmt.Printf("Invalid -persona value: %v\n", err)
+		os.Exit(ExitInputError)
+	}
+	cfg.Personas = personas
+
+	if cfg.URLBuffer < 0 || cfg.ResultBuffer < 0 {
+		fmt.Println("Invalid -url-buffer or -result-buffer value, it must not be negative")
+		os.Exit(ExitInputError)
+	}
+	if cfg.ResultBuffer == 0 {
+		cfg.ResultBuffer = cfg.Concurrency
+	}
+
+	if cfg.AdmissionThreshold < 0 || cfg.AdmissionThreshold > 1 {
+		fmt.Println("Invalid -admission-threshold value, it must be between 0 and 1")
+		os.Exit(ExitInputError)
+	}
+
+	if cfg.NormalizeBody || len(cfg.NormalizeRegexes) > 0 {
+		defaults := make([]*regexp.Regexp, 0, len(defaultNormalizePatterns))
+		for _, pattern := range defaultNormalizePatterns {
+			defaults = append(defaults, regexp.MustCompile(pattern))
+		}
+		cfg.NormalizeRegexes = append(defaults, cfg.NormalizeRegexes...)
+		cfg.NormalizeBody = true
+	}
+
+	if cfg.WildcardRatio < 0 || cfg.WildcardRatio > 1 {
+		fmt.Println("Invalid -wildcard-ratio value, it must be between 0 and 1")
+		os.Exit(ExitInputError)
+	}
+
+	if cfg.WAFRate < 0 {
+		fmt.Println("Invalid -waf-rate value, it must not be negative")
+		os.Exit(ExitInputError)
+	}
+
+	for _, provider := range cfg.CloudStorage {
+		if !cloudStorageProviders[provider] {
+			fmt.Printf("Invalid -cloud-storage value '%s', supported providers: s3, azure, gcs\n", provider)
+			os.Exit(ExitInputError)
+		}
+	}
+
+	for _, set := range cfg.Checks {
+		if !checkSets[set] {
+			fmt.Printf("Invalid -checks value '%s', supported check sets: vcs, openapi, graphql\n", set)
+			os.Exit(ExitInputError)
+		}
+	}
+
+	// Tokens are only read from the environment, so they don't show up in process lists
+	if cfg.GitHubIssuesRepo != "" {
+		if parts := strings.Split(cfg.GitHubIssuesRepo, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			fmt.Println("Invalid -github-issues value, it must be owner/name")
+			os.Exit(ExitInputError)
+		}
+		cfg.GitHubToken = os.Getenv("GITHUB_TOKEN")
+		if cfg.GitHubToken == "" {
+			fmt.Println("-github-issues requires the GITHUB_TOKEN environment variable")
+			os.Exit(ExitInputError)
+		}
+	}
+
+	if cfg.JiraProject != "" {
+		cfg.JiraUser = os.Getenv("JIRA_USER")
+		cfg.JiraToken = os.Getenv("JIRA_TOKEN")
+		if cfg.JiraURL == "" || cfg.JiraUser == "" || cfg.JiraToken == "" {
+			fmt.Println("-jira-issues requires -jira-url and the JIRA_USER and JIRA_TOKEN environment variables")
+			os.Exit(ExitInputError)
+		}
+	}
+
+	if cfg.KafkaSASL != "" {
+		if cfg.KafkaSASL != "plain" && cfg.KafkaSASL != "scram-sha-256" && cfg.KafkaSASL != "scram-sha-512" {
+			fmt.Printf("Invalid -kafka-sasl value '%s', allowed values are 'plain', 'scram-sha-256' and 'scram-sha-512'\n", cfg.KafkaSASL)
+			os.Exit(ExitInputError)
+		}
+		cfg.KafkaUser = os.Getenv("KAFKA_USER")
+		cfg.KafkaPassword = os.Getenv("KAFKA_PASSWORD")
+		if cfg.KafkaUser == "" || cfg.KafkaPassword == "" {
+			fmt.Println("-kafka-sasl requires the KAFKA_USER and KAFKA_PASSWORD environment variables")
+			os.Exit(ExitInputError)
+		}
 	}
 
 	if proxyURLStr != "" {
 		proxyURL, err := url.Parse(proxyURLStr)
 		if err != nil {
 			fmt.Printf("Invalid proxy URL: %v\n", err)
-			os.Exit(1)
+			os.Exit(ExitInputError)
 		}
 		cfg.ProxyURL = proxyURL
 	}
 
+	var proxies []*url.URL
+	if cfg.ProxyURL != nil {
+		proxies = append(proxies, cfg.ProxyURL)
+	}
+	if cfg.ProxyFile != "" {
+		lines, err := utils.ReadLines(cfg.ProxyFile)
+		if err != nil {
+			fmt.Printf("Error reading -proxy-file: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			proxyURL, err := url.Parse(line)
+			if err != nil || proxyURL.Host == "" {
+				fmt.Printf("Invalid proxy URL in %s: %s\n", cfg.ProxyFile, line)
+				os.Exit(ExitInputError)
+			}
+			proxies = append(proxies, proxyURL)
+		}
+		if len(proxies) == 0 {
+			fmt.Printf("%s contains no proxies\n", cfg.ProxyFile)
+			os.Exit(ExitInputError)
+		}
+	}
+	if len(proxies) > 0 && cfg.FastHTTP {
+		fmt.Println("-proxy and -proxy-file cannot be combined with -use-fasthttp, the fasthttp client does not support proxies")
+		os.Exit(ExitInputError)
+	}
+	if cfg.PreviousStoreFile != "" {
+		conditions, err := request.ReadConditions(cfg.PreviousStoreFile)
+		if err != nil {
+			fmt.Printf("Error reading -previous-store %s: %v\n", cfg.PreviousStoreFile, err)
+			os.Exit(ExitInputError)
+		}
+		cfg.Conditions = conditions
+	}
+
+	if cfg.Monitor {
+		if cfg.MonitorInterval <= 0 {
+			fmt.Println("-interval must be positive")
+			os.Exit(ExitInputError)
+		}
+		// The -dedup-db would suppress the findings of all iterations after the first
+		if cfg.Mode != "" || cfg.DomainsFile == "-" || cfg.ResumeFile != "" || cfg.DedupDB != "" {
+			fmt.Println("-monitor cannot be combined with serve or agent mode, domains from stdin, -resume-file or -dedup-db")
+			os.Exit(ExitInputError)
+		}
+		if cfg.Conditions == nil {
+			cfg.Conditions = request.NewConditions()
+		}
+	}
+
+	if cfg.DoH != "" {
+		resolver, err := doh.NewResolver(cfg.DoH, cfg.Timeout)
+		if err != nil {
+			fmt.Printf("Invalid -doh endpoint: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		cfg.Resolver = resolver
+	}
+
+	if len(proxies) > 0 {
+		if cfg.ProxyCheckInterval <= 0 {
+			fmt.Println("-proxy-check-interval must be positive")
+			os.Exit(ExitInputError)
+		}
+		cfg.Proxies = proxy.NewPool(proxies, cfg.Verbose)
+	}
+
 	if extraHeaders != "" {
 		headers := strings.Split(extraHeaders, ",")
 		for _, header := range headers {
@@ -112,15 +825,57 @@ func ParseFlags() Config {
 		}
 	}
 
+	if header := strings.TrimSpace(strings.TrimPrefix(cfg.DedupBy, "header:")); strings.HasPrefix(cfg.DedupBy, "header:") && header != "" {
+		cfg.DedupHeader = textproto.CanonicalMIMEHeaderKey(header)
+		cfg.DedupBy = "header"
+	} else if cfg.DedupBy != "size" && cfg.DedupBy != "hash" {
+		fmt.Printf("Invalid -dedup-by value '%s', allowed values are 'size', 'hash' and 'header:<name>'\n", cfg.DedupBy)
+		os.Exit(ExitInputError)
+	}
+
 	if cfg.BasePathsFile != "" {
 		var err error
 		cfg.BasePaths, err = readBasePaths(cfg.BasePathsFile)
 		if err != nil {
 			fmt.Printf("Error reading base paths file: %v\n", err)
-			os.Exit(1)
+			os.Exit(ExitInputError)
 		}
 	}
 
+	if cfg.APITemplatesFile != "" {
+		templates, err := readAPITemplates(cfg.APITemplatesFile)
+		if err != nil {
+			fmt.Printf("Error reading API templates file: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		cfg.APITemplates = templates
+		cfg.APIMode = true
+	} else if cfg.APIMode {
+		cfg.APITemplates = request.DefaultTemplates
+	}
+
+	if cfg.BurpFile != "" {
+		targets, dirs, err := importer.ReadBurp(cfg.BurpFile)
+		if err != nil {
+			fmt.Printf("Error reading Burp export: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		cfg.ImportedTargets = append(cfg.ImportedTargets, targets...)
+		if cfg.BurpDirs {
+			cfg.BasePaths = utils.UniqueStrings(append(cfg.BasePaths, dirs...))
+		}
+	}
+
+	if cfg.InputHttpxFile != "" {
+		targets, words, err := importer.ReadHttpx(cfg.InputHttpxFile)
+		if err != nil {
+			fmt.Printf("Error reading httpx output: %v\n", err)
+			os.Exit(ExitInputError)
+		}
+		cfg.ImportedTargets = append(cfg.ImportedTargets, targets...)
+		cfg.HostWords = words
+	}
+
 	if cfg.EnvAppendWords == "" {
 		// Use the default if user did not supply anything
 		cfg.AppendEnvList = defaultAppendEnvList
@@ -136,6 +891,105 @@ func ParseFlags() Config {
 	return cfg
 }
 
+// cloudStorageProviders are the providers pkg/cloudstorage can scan
+var cloudStorageProviders = map[string]bool{"s3": true, "azure": true, "gcs": true}
+
+// checkSets are the check sets pkg/checks provides
+var checkSets = map[string]bool{"vcs": true, "openapi": true, "graphql": true}
+
+// HasDomainInput reports whether any source of domains to scan was given
+func (cfg Config) HasDomainInput() bool {
+	return cfg.DomainsFile != "" || cfg.Domain != "" || cfg.BurpFile != "" || cfg.InputHttpxFile != ""
+}
+
+// ReadLimit returns how many body bytes of a response with contentType are read: the limit of the
+// longest -content-read-limits prefix of its media type, -max-content-read if none matches
+func (cfg Config) ReadLimit(contentType string) int64 {
+	mediaType := strings.ToLower(strings.TrimSpace(contentType))
+	limit, matched := cfg.MaxContentRead, ""
+	for prefix, prefixLimit := range cfg.ContentReadLimits {
+		if strings.HasPrefix(mediaType, prefix) && len(prefix) > len(matched) {
+			limit, matched = prefixLimit, prefix
+		}
+	}
+	return limit
+}
+
+// RequestReadLimit returns the largest read limit of any content type. It is requested with the
+// Range header, before the content type of the response is known.
+func (cfg Config) RequestReadLimit() int64 {
+	limit := cfg.MaxContentRead
+	for _, prefixLimit := range cfg.ContentReadLimits {
+		if prefixLimit > limit {
+			limit = prefixLimit
+		}
+	}
+	return limit
+}
+
+// defaultNormalizePatterns match the dynamic parts of pages stripped by -normalize-body. Tokens are
+// removed together with their attribute or parameter name.
+var defaultNormalizePatterns = []string{
+	// ISO 8601 and RFC 1123 timestamps, times of day
+	`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?`,
+	`(?i)(mon|tue|wed|thu|fri|sat|sun), \d{2} [a-z]{3} \d{4} \d{2}:\d{2}:\d{2} [a-z]{3}`,
+	`\b\d{1,2}:\d{2}:\d{2}\b`,
+	// Unix timestamps in seconds or milliseconds
+	`\b1\d{9}(\d{3})?\b`,
+	// CSRF tokens and nonces in hidden inputs, meta tags and script attributes
+	`(?i)(csrf|xsrf|authenticity_token|_token|__requestverificationtoken|__viewstate|__eventvalidation)[^>]{0,60}?(value|content)\s*=\s*["'][^"']*["']`,
+	`(?i)nonce\s*=\s*["'][^"']*["']`,
+	// Session IDs in URLs and scripts
+	`(?i)(jsessionid|phpsessid|aspsessionid[a-z]*|sessionid|sid)=[a-z0-9._-]+`,
+	`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`,
+}
+
+// Target is a scheme and port combination hosts are requested on
+type Target struct {
+	Scheme string
+	Port   int
+}
+
+// Host returns host with the port of t, the default port of the scheme is omitted
+func (t Target) Host(host string) string {
+	if (t.Scheme == "https" && t.Port == 443) || (t.Scheme == "http" && t.Port == 80) {
+		return host
+	}
+	return host + ":" + strconv.Itoa(t.Port)
+}
+
+// ParseMatrix parses scheme and port combinations like "http:80,8080;https:443,8443"
+func ParseMatrix(value string) ([]Target, error) {
+	var targets []Target
+	seen := make(map[Target]bool)
+	for _, group := range strings.Split(value, ";") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+		scheme, ports, found := strings.Cut(group, ":")
+		scheme = strings.ToLower(strings.TrimSpace(scheme))
+		if !found || (scheme != "http" && scheme != "https") {
+			return nil, fmt.Errorf("'%s' is not like http:80,8080 or https:443", group)
+		}
+		for _, portStr := range splitCSV(ports) {
+			port, err := strconv.Atoi(portStr)
+			if err != nil || port < 1 || port > 65535 {
+				return nil, fmt.Errorf("invalid port '%s' for %s", portStr, scheme)
+			}
+			target := Target{Scheme: scheme, Port: port}
+			if !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no scheme and port given")
+	}
+	return targets, nil
+}
+
 func noRulesSpecified(cfg Config) bool {
 	noRules := true
 
@@ -155,9 +1009,125 @@ func noRulesSpecified(cfg Config) bool {
 		noRules = false
 	}
 
+	if cfg.BaselineDiff {
+		noRules = false
+	}
+
+	if cfg.TitleRegex != nil {
+		noRules = false
+	}
+
+	if cfg.MatchExpr != nil {
+		noRules = false
+	}
+
+	if cfg.DetectTypes != "" {
+		noRules = false
+	}
+
+	if len(cfg.MatchFaviconHashes) > 0 {
+		noRules = false
+	}
+
+	if len(cfg.MatchServers) > 0 {
+		noRules = false
+	}
+
+	if len(cfg.AnalyzerPlugins) > 0 {
+		noRules = false
+	}
+
+	if len(cfg.CloudStorage) > 0 {
+		noRules = false
+	}
+
+	if len(cfg.Checks) > 0 {
+		noRules = false
+	}
+
 	return noRules
 }
 
+// loopbackAddr reports whether the listen address addr only accepts local connections
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// parseByteSize parses sizes like 512MB, 1.4GB or plain byte counts
+func parseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+
+	multiplier := float64(1)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	} {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.multiplier
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid size '%s'", value)
+	}
+
+	return int64(number * multiplier), nil
+}
+
+func splitCSV(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// readAPITemplates reads one JSON body per line, empty lines and lines starting with # are skipped
+func readAPITemplates(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var templates []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		template := strings.TrimSpace(scanner.Text())
+		if template == "" || strings.HasPrefix(template, "#") {
+			continue
+		}
+		if err := request.ValidateTemplate(template); err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("%s contains no templates", filename)
+	}
+	return templates, nil
+}
+
 func readBasePaths(filename string) ([]string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
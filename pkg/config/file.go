@@ -0,0 +1,126 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// loadConfigFile reads a YAML or TOML file whose keys are the names of the command-line flags
+// (without the leading dash) and applies every value whose flag was not set on the command line.
+// If profile is set, the values of that entry in the "profiles" section override the top-level values.
+func loadConfigFile(filename, profile string) error {
+	values, err := readConfigFile(filename)
+	if err != nil {
+		return err
+	}
+
+	profiles, _ := values["profiles"].(map[string]interface{})
+	delete(values, "profiles")
+
+	if profile != "" {
+		profileValues, exists := profiles[profile].(map[string]interface{})
+		if !exists {
+			return fmt.Errorf("profile '%s' not found in %s (available: %s)", profile, filename, strings.Join(profileNames(profiles), ", "))
+		}
+		for key, value := range profileValues {
+			values[key] = value
+		}
+	}
+
+	return applyValues(values)
+}
+
+func profileNames(profiles map[string]interface{}) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readConfigFile(filename string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".toml":
+		if _, err := toml.Decode(string(data), &values); err != nil {
+			return nil, fmt.Errorf("invalid toml in %s: %w", filename, err)
+		}
+	case ".yaml", ".yml", "":
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("invalid yaml in %s: %w", filename, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file type %s (use .yaml, .yml or .toml)", filename)
+	}
+
+	return values, nil
+}
+
+func applyValues(values map[string]interface{}) error {
+	setOnCommandLine := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+
+	// Sorted to make errors reproducible
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == "config" || key == "profile" {
+			continue
+		}
+
+		if flag.Lookup(key) == nil {
+			return fmt.Errorf("unknown config key '%s'", key)
+		}
+
+		if setOnCommandLine[key] {
+			continue
+		}
+
+		if err := flag.Set(key, flagValue(values[key])); err != nil {
+			return fmt.Errorf("invalid value for config key '%s': %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// flagValue converts a decoded value to its command-line representation, lists become csv
+func flagValue(value interface{}) string {
+	switch typed := value.(type) {
+	case []interface{}:
+		parts := make([]string, 0, len(typed))
+		for _, v := range typed {
+			parts = append(parts, flagValue(v))
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}:
+		parts := make([]string, 0, len(typed))
+		for k, v := range typed {
+			parts = append(parts, k+":"+flagValue(v))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(typed)
+	}
+}
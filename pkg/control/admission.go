@@ -0,0 +1,102 @@
+package control
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	admissionInterval     = 500 * time.Millisecond
+	pressureReadDivisor   = 4
+	minPressureReadLength = 64 * 1024
+)
+
+// Admission watches the heap against the memory limit. While the heap is above the threshold the
+// URL generation is paused and, if enabled, less of each response body is read.
+type Admission struct {
+	memoryLimit int64
+	threshold   float64
+	shrinkReads bool
+	verbose     bool
+
+	pressure int32
+}
+
+func NewAdmission(memoryLimit int64, threshold float64, shrinkReads, verbose bool) *Admission {
+	return &Admission{
+		memoryLimit: memoryLimit,
+		threshold:   threshold,
+		shrinkReads: shrinkReads,
+		verbose:     verbose,
+	}
+}
+
+// Run samples the heap periodically until ctx is cancelled
+func (a *Admission) Run(ctx context.Context) {
+	ticker := time.NewTicker(admissionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			atomic.StoreInt32(&a.pressure, 0)
+			return
+		case <-ticker.C:
+			a.sample()
+		}
+	}
+}
+
+func (a *Admission) sample() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	pressure := int32(0)
+	if float64(stats.HeapAlloc) > a.threshold*float64(a.memoryLimit) {
+		pressure = 1
+	}
+
+	previous := atomic.SwapInt32(&a.pressure, pressure)
+	if a.verbose && previous != pressure {
+		if pressure == 1 {
+			log.Printf("Memory pressure: heap at %d MB, pausing URL generation\n", stats.HeapAlloc>>20)
+		} else {
+			log.Printf("Memory pressure relieved: heap at %d MB, resuming URL generation\n", stats.HeapAlloc>>20)
+		}
+	}
+}
+
+// UnderPressure reports whether the heap is above the threshold
+func (a *Admission) UnderPressure() bool {
+	return a != nil && atomic.LoadInt32(&a.pressure) == 1
+}
+
+// Wait blocks while the heap is above the threshold or until ctx is cancelled
+func (a *Admission) Wait(ctx context.Context) {
+	for a.UnderPressure() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(admissionInterval):
+		}
+	}
+}
+
+// ContentReadLimit returns how many body bytes a request may read, given the configured maximum
+func (a *Admission) ContentReadLimit(max int64) int64 {
+	if !a.UnderPressure() || !a.shrinkReads {
+		return max
+	}
+
+	limit := max / pressureReadDivisor
+	if limit < minPressureReadLength {
+		limit = minPressureReadLength
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
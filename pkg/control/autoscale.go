@@ -0,0 +1,160 @@
+package control
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+const (
+	autoscaleInterval  = 5 * time.Second
+	highErrorRate      = 0.2
+	lowErrorRate       = 0.05
+	latencyFactor      = 2.0
+	memoryPressureRate = 0.9
+)
+
+// Autoscaler limits how many workers may send requests at the same time and adjusts that
+// limit based on the observed error rate, latency and memory usage.
+type Autoscaler struct {
+	maxWorkers  int
+	memoryLimit int64
+	verbose     bool
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+
+	requests        int
+	errors          int
+	totalLatency    time.Duration
+	baselineLatency time.Duration
+}
+
+func NewAutoscaler(maxWorkers int, memoryLimit int64, verbose bool) *Autoscaler {
+	a := &Autoscaler{
+		maxWorkers:  maxWorkers,
+		memoryLimit: memoryLimit,
+		verbose:     verbose,
+		limit:       (maxWorkers + 1) / 2,
+	}
+	a.cond = sync.NewCond(&a.mu)
+	return a
+}
+
+// Acquire blocks until the worker may send a request. Every Acquire must be followed by Release.
+func (a *Autoscaler) Acquire() {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	for a.active >= a.limit {
+		a.cond.Wait()
+	}
+	a.active++
+	a.mu.Unlock()
+}
+
+func (a *Autoscaler) Release() {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	a.active--
+	a.mu.Unlock()
+	a.cond.Signal()
+}
+
+// Observe records the outcome of a request
+func (a *Autoscaler) Observe(res result.Result) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.requests++
+	if res.Error != nil {
+		a.errors++
+	}
+	a.totalLatency += res.Duration
+}
+
+// Run adjusts the limit periodically until ctx is cancelled, then lifts it so no worker stays blocked.
+func (a *Autoscaler) Run(ctx context.Context) {
+	ticker := time.NewTicker(autoscaleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.mu.Lock()
+			a.limit = a.maxWorkers
+			a.mu.Unlock()
+			a.cond.Broadcast()
+			return
+		case <-ticker.C:
+			a.adjust()
+		}
+	}
+}
+
+func (a *Autoscaler) adjust() {
+	memoryPressure := false
+	if a.memoryLimit > 0 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		memoryPressure = float64(stats.HeapAlloc) > memoryPressureRate*float64(a.memoryLimit)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.requests == 0 {
+		return
+	}
+
+	errorRate := float64(a.errors) / float64(a.requests)
+	latency := a.totalLatency / time.Duration(a.requests)
+	if a.baselineLatency == 0 || latency < a.baselineLatency {
+		a.baselineLatency = latency
+	}
+	slow := float64(latency) > latencyFactor*float64(a.baselineLatency)
+
+	previous := a.limit
+	switch {
+	case errorRate > highErrorRate || slow || memoryPressure:
+		a.limit = a.limit * 3 / 4
+		if a.limit < 1 {
+			a.limit = 1
+		}
+	case errorRate < lowErrorRate:
+		step := a.limit / 10
+		if step < 1 {
+			step = 1
+		}
+		a.limit += step
+		if a.limit > a.maxWorkers {
+			a.limit = a.maxWorkers
+		}
+	}
+
+	if a.verbose && a.limit != previous {
+		log.Printf("Autoscale: %d -> %d workers (errors: %.1f%%, latency: %s, memory pressure: %v)\n",
+			previous, a.limit, errorRate*100, latency.Round(time.Millisecond), memoryPressure)
+	}
+
+	a.requests, a.errors, a.totalLatency = 0, 0, 0
+
+	if a.limit > previous {
+		a.cond.Broadcast()
+	}
+}
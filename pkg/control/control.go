@@ -0,0 +1,94 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"golang.org/x/time/rate"
+)
+
+const rateStep = 1.25
+
+// Controller lets the user pause/resume the scan and adjust the request rate while it is running.
+type Controller struct {
+	limiter *rate.Limiter
+
+	mu      sync.Mutex
+	paused  bool
+	resumed chan struct{}
+}
+
+func NewController(limiter *rate.Limiter) *Controller {
+	return &Controller{
+		limiter: limiter,
+		resumed: make(chan struct{}),
+	}
+}
+
+// Listen reads commands line by line from r until it is closed:
+// p = pause, r = resume, + = increase rate, - = decrease rate
+func (c *Controller) Listen(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		for _, key := range strings.TrimSpace(scanner.Text()) {
+			c.handle(key)
+		}
+	}
+}
+
+func (c *Controller) handle(key rune) {
+	switch key {
+	case 'p':
+		c.Pause()
+		color.Yellow("\n[!] Scan paused, press r + enter to resume")
+	case 'r':
+		c.Resume()
+		color.Yellow("\n[!] Scan resumed")
+	case '+':
+		c.limiter.SetLimit(c.limiter.Limit() * rateStep)
+		color.Yellow("\n[!] Rate increased to %.2f requests/s", float64(c.limiter.Limit()))
+	case '-':
+		c.limiter.SetLimit(c.limiter.Limit() / rateStep)
+		color.Yellow("\n[!] Rate decreased to %.2f requests/s", float64(c.limiter.Limit()))
+	}
+}
+
+func (c *Controller) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = true
+}
+
+func (c *Controller) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		c.paused = false
+		close(c.resumed)
+		c.resumed = make(chan struct{})
+	}
+}
+
+// Wait blocks while the scan is paused. It returns early if ctx is cancelled.
+func (c *Controller) Wait(ctx context.Context) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	if !c.paused {
+		c.mu.Unlock()
+		return
+	}
+	resumed := c.resumed
+	c.mu.Unlock()
+
+	select {
+	case <-resumed:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,167 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+const (
+	pollInterval = 2 * time.Second
+	maxFailures  = 10
+)
+
+// Agent pulls URL batches from a controller started in serve mode, executes them and posts the
+// results back until the controller reports that the scan is done.
+type Agent struct {
+	controller  string
+	token       string
+	batchSize   int
+	concurrency int
+	verbose     bool
+	httpClient  *http.Client
+}
+
+func NewAgent(controller, token string, batchSize, concurrency int, verbose bool) *Agent {
+	return &Agent{
+		controller:  strings.TrimSuffix(controller, "/"),
+		token:       token,
+		batchSize:   batchSize,
+		concurrency: concurrency,
+		verbose:     verbose,
+		httpClient:  &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Run processes batches with execute until the controller is done, ctx is cancelled or the
+// controller could not be reached maxFailures times in a row.
+func (a *Agent) Run(ctx context.Context, execute func(url string) result.Result) error {
+	failures := 0
+
+	for ctx.Err() == nil {
+		b, err := a.fetchBatch(ctx)
+		if err != nil {
+			failures++
+			if failures >= maxFailures {
+				return fmt.Errorf("controller unreachable: %w", err)
+			}
+			if a.verbose {
+				log.Printf("Fetching batch failed: %v\n", err)
+			}
+			a.sleep(ctx)
+			continue
+		}
+		failures = 0
+
+		if b.Done {
+			return nil
+		}
+		if len(b.URLs) == 0 {
+			a.sleep(ctx)
+			continue
+		}
+
+		results := a.execute(b.URLs, execute)
+
+		// The controller hands the batch out again after the lease expired, so a failed post
+		// only costs time
+		if err := a.postResults(ctx, results); err != nil && a.verbose {
+			log.Printf("Posting results failed: %v\n", err)
+		}
+	}
+
+	return ctx.Err()
+}
+
+func (a *Agent) execute(urls []string, execute func(url string) result.Result) []wireResult {
+	results := make([]wireResult, len(urls))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < a.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = toWire(execute(urls[index]))
+			}
+		}()
+	}
+
+	for i := range urls {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+func (a *Agent) fetchBatch(ctx context.Context) (batch, error) {
+	var b batch
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s%s?size=%d", a.controller, batchPath, a.batchSize), nil)
+	if err != nil {
+		return b, err
+	}
+	resp, err := a.do(req)
+	if err != nil {
+		return b, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&b)
+	return b, err
+}
+
+func (a *Agent) postResults(ctx context.Context, results []wireResult) error {
+	body, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.controller+resultsPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (a *Agent) do(req *http.Request) (*http.Response, error) {
+	if a.token != "" {
+		req.Header.Set(tokenHeader, a.token)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("controller returned %s", resp.Status)
+	}
+
+	return resp, nil
+}
+
+func (a *Agent) sleep(ctx context.Context) {
+	select {
+	case <-time.After(pollInterval):
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,92 @@
+package distributed
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+const (
+	batchPath   = "/batch"
+	resultsPath = "/results"
+	tokenHeader = "X-DFS-Token"
+)
+
+type batch struct {
+	URLs []string `json:"urls"`
+	Done bool     `json:"done"`
+}
+
+// wireResult is the JSON representation of result.Result sent from agents to the controller
+type wireResult struct {
+	URL                 string              `json:"url"`
+	Content             string              `json:"content"`
+	Error               string              `json:"error,omitempty"`
+	StatusCode          int                 `json:"status"`
+	FileSize            int64               `json:"size"`
+	ContentType         string              `json:"content_type"`
+	DurationMs          int64               `json:"duration_ms"`
+	DiffersFromBaseline bool                `json:"differs_from_baseline,omitempty"`
+	SoftNotFound        bool                `json:"soft_404,omitempty"`
+	Server              string              `json:"server,omitempty"`
+	Certificate         *result.Certificate `json:"certificate,omitempty"`
+	DedupHeader         string              `json:"dedup_header,omitempty"`
+	TransferSize        int64               `json:"transfer_size,omitempty"`
+	ETag                string              `json:"etag,omitempty"`
+	LastModified        string              `json:"last_modified,omitempty"`
+	Unchanged           bool                `json:"unchanged,omitempty"`
+}
+
+func toWire(res result.Result) wireResult {
+	w := wireResult{
+		URL:                 res.URL,
+		Content:             res.Content,
+		StatusCode:          res.StatusCode,
+		FileSize:            res.FileSize,
+		ContentType:         res.ContentType,
+		DurationMs:          res.Duration.Milliseconds(),
+		DiffersFromBaseline: res.DiffersFromBaseline,
+		SoftNotFound:        res.SoftNotFound,
+		Server:              res.Server,
+		Certificate:         res.Certificate,
+		DedupHeader:         res.DedupHeader,
+		TransferSize:        res.TransferSize,
+		ETag:                res.ETag,
+		LastModified:        res.LastModified,
+		Unchanged:           res.Unchanged,
+	}
+	if res.Error != nil {
+		w.Error = res.Error.Error()
+	}
+	return w
+}
+
+func (w wireResult) toResult() result.Result {
+	res := result.Result{
+		URL:                 w.URL,
+		Content:             w.Content,
+		StatusCode:          w.StatusCode,
+		FileSize:            w.FileSize,
+		ContentType:         w.ContentType,
+		Duration:            time.Duration(w.DurationMs) * time.Millisecond,
+		DiffersFromBaseline: w.DiffersFromBaseline,
+		SoftNotFound:        w.SoftNotFound,
+		Server:              w.Server,
+		Certificate:         w.Certificate,
+		DedupHeader:         w.DedupHeader,
+		TransferSize:        w.TransferSize,
+		ETag:                w.ETag,
+		LastModified:        w.LastModified,
+		Unchanged:           w.Unchanged,
+	}
+	if prefix := strings.TrimSuffix(w.Error, result.ErrTarpit.Error()); prefix != w.Error {
+		// Tarpits stay detectable with errors.Is for the status counters
+		res.Error = fmt.Errorf("%s%w", prefix, result.ErrTarpit)
+	} else if w.Error != "" {
+		res.Error = errors.New(w.Error)
+	}
+	return res
+}
@@ -0,0 +1,285 @@
+package distributed
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	publishBatchSize = 500
+	consumeBatchSize = 50
+	// producerTTL is the time a publishing instance stays registered without refreshing its
+	// registration, so a crashed producer does not keep the consumers waiting forever
+	producerTTL = 30 * time.Second
+)
+
+// popScript moves up to ARGV[2] URLs from the queue into the processing set, scored by their deadline
+var popScript = redis.NewScript(`
+local urls = redis.call('LPOP', KEYS[1], ARGV[2])
+if not urls then
+	return {}
+end
+for _, url in ipairs(urls) do
+	redis.call('ZADD', KEYS[2], ARGV[1], url)
+end
+return urls
+`)
+
+// requeueScript moves URLs whose deadline passed back into the queue
+var requeueScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, url in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], url)
+	redis.call('RPUSH', KEYS[1], url)
+end
+return #expired
+`)
+
+type redisFinding struct {
+	URL       string `json:"url"`
+	Detection string `json:"detection"`
+	SHA256    string `json:"sha256,omitempty"`
+	Title     string `json:"title,omitempty"`
+}
+
+// RedisQueue shares the generated URLs and the findings of independent instances through Redis.
+// URLs which are not acknowledged within the visibility timeout (e.g. because the instance
+// crashed) are put back into the queue. Publishing instances register as producers, the consumers
+// only finish once no producer is registered and the queue stayed empty for the idle timeout.
+type RedisQueue struct {
+	client            *redis.Client
+	id                string
+	urlsKey           string
+	processingKey     string
+	findingsKey       string
+	producersKey      string
+	visibilityTimeout time.Duration
+	idleTimeout       time.Duration
+	verbose           bool
+}
+
+func NewRedisQueue(redisURL, prefix string, visibilityTimeout, idleTimeout time.Duration, verbose bool) (*RedisQueue, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(options)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if err := checkRedisVersion(client); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &RedisQueue{
+		client:            client,
+		id:                hex.EncodeToString(id),
+		urlsKey:           prefix + ":urls",
+		processingKey:     prefix + ":processing",
+		findingsKey:       prefix + ":findings",
+		producersKey:      prefix + ":producers",
+		visibilityTimeout: visibilityTimeout,
+		idleTimeout:       idleTimeout,
+		verbose:           verbose,
+	}, nil
+}
+
+// checkRedisVersion fails for servers older than Redis 6.2, which lack the count argument of the
+// LPOP of popScript. Servers which do not report a version are accepted.
+func checkRedisVersion(client *redis.Client) error {
+	info, err := client.Info(context.Background(), "server").Result()
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "redis_version:") {
+			continue
+		}
+		version := strings.TrimPrefix(line, "redis_version:")
+		parts := strings.SplitN(version, ".", 3)
+		if len(parts) < 2 {
+			return nil
+		}
+		major, majorErr := strconv.Atoi(parts[0])
+		minor, minorErr := strconv.Atoi(parts[1])
+		if majorErr == nil && minorErr == nil && (major < 6 || (major == 6 && minor < 2)) {
+			return fmt.Errorf("Redis %s is not supported, -redis requires Redis 6.2 or newer", version)
+		}
+		return nil
+	}
+	return nil
+}
+
+// Publish pushes all URLs from urls into the shared queue. The instance is registered as producer
+// from the first URL until it returns. It returns on the first error, the caller has to stop the
+// sender of urls.
+func (q *RedisQueue) Publish(ctx context.Context, urls <-chan string) error {
+	batch := make([]interface{}, 0, publishBatchSize)
+
+	registered := false
+	defer func() {
+		if registered {
+			q.client.ZRem(context.Background(), q.producersKey, q.id)
+		}
+	}()
+	refresh := time.NewTicker(producerTTL / 3)
+	defer refresh.Stop()
+
+	for url := range urls {
+		select {
+		case <-refresh.C:
+			registered = false
+		default:
+		}
+		if !registered {
+			if err := q.client.ZAdd(ctx, q.producersKey, redis.Z{Score: float64(time.Now().Add(producerTTL).Unix()), Member: q.id}).Err(); err != nil {
+				return err
+			}
+			registered = true
+		}
+
+		batch = append(batch, url)
+		if len(batch) == publishBatchSize {
+			if err := q.client.RPush(ctx, q.urlsKey, batch...).Err(); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		return q.client.RPush(ctx, q.urlsKey, batch...).Err()
+	}
+	return nil
+}
+
+// Consume sends URLs from the shared queue to out until ctx is cancelled or publishing is closed
+// and neither queued nor unacknowledged URLs are left. It closes out when it returns.
+func (q *RedisQueue) Consume(ctx context.Context, out chan<- string, publishing <-chan struct{}) error {
+	defer close(out)
+
+	var idleSince time.Time
+	for ctx.Err() == nil {
+		deadline := time.Now().Add(q.visibilityTimeout).Unix()
+		urls, err := popScript.Run(ctx, q.client, []string{q.urlsKey, q.processingKey}, deadline, consumeBatchSize).StringSlice()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		for _, url := range urls {
+			select {
+			case out <- url:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		if len(urls) > 0 {
+			idleSince = time.Time{}
+			continue
+		}
+
+		if err := requeueScript.Run(ctx, q.client, []string{q.urlsKey, q.processingKey}, time.Now().Unix()).Err(); err != nil && err != redis.Nil {
+			return err
+		}
+
+		finished, err := q.finished(ctx, publishing)
+		if err != nil {
+			return err
+		}
+		// Producers which start later or between two producers must not find the consumers gone
+		if !finished {
+			idleSince = time.Time{}
+		} else if idleSince.IsZero() {
+			idleSince = time.Now()
+		} else if time.Since(idleSince) >= q.idleTimeout {
+			return nil
+		}
+
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+		}
+	}
+
+	return nil
+}
+
+func (q *RedisQueue) finished(ctx context.Context, publishing <-chan struct{}) (bool, error) {
+	select {
+	case <-publishing:
+	default:
+		return false, nil
+	}
+
+	if err := q.client.ZRemRangeByScore(ctx, q.producersKey, "-inf", strconv.FormatInt(time.Now().Unix(), 10)).Err(); err != nil {
+		return false, err
+	}
+	producers, err := q.client.ZCard(ctx, q.producersKey).Result()
+	if err != nil || producers > 0 {
+		return false, err
+	}
+
+	queued, err := q.client.LLen(ctx, q.urlsKey).Result()
+	if err != nil {
+		return false, err
+	}
+	processing, err := q.client.ZCard(ctx, q.processingKey).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return queued == 0 && processing == 0, nil
+}
+
+// Ack marks url as processed so it is not handed out again
+func (q *RedisQueue) Ack(url string) {
+	if q == nil {
+		return
+	}
+	if err := q.client.ZRem(context.Background(), q.processingKey, url).Err(); err != nil && q.verbose {
+		// The URL is handed out again after the visibility timeout
+		log.Printf("Could not acknowledge %s in Redis: %v\n", url, err)
+	}
+}
+
+// PublishFinding pushes a finding as JSON into the shared findings list
+func (q *RedisQueue) PublishFinding(finding result.Finding) error {
+	if q == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(redisFinding{
+		URL:       finding.URL,
+		Detection: finding.Detection,
+		SHA256:    finding.SHA256,
+		Title:     finding.Title,
+	})
+	if err != nil {
+		return err
+	}
+	return q.client.RPush(context.Background(), q.findingsKey, data).Err()
+}
+
+func (q *RedisQueue) Close() error {
+	return q.client.Close()
+}
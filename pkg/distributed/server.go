@@ -0,0 +1,217 @@
+package distributed
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+const (
+	defaultBatchSize = 50
+	maxBatchSize     = 1000
+	// maxResultsBodySize bounds the results an agent posts at once, a full batch of bodies cut at the
+	// default -max-content-read stays far below it in practice
+	maxResultsBodySize = 512 << 20
+)
+
+// Server hands out batches of generated URLs to agents and feeds the returned results into the
+// regular result processing. URLs of batches which are not answered within leaseTimeout are
+// handed out again, so crashed agents do not lose work.
+type Server struct {
+	urls           <-chan string
+	results        chan<- result.Result
+	token          string
+	leaseTimeout   time.Duration
+	processedCount *int64
+
+	httpServer *http.Server
+	listener   net.Listener
+
+	mu          sync.Mutex
+	outstanding map[string]time.Time
+	// sending counts the results taken from outstanding which are not yet in the results channel,
+	// the channel must not be closed before they are
+	sending   int
+	drained   bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewServer(addr string, urls <-chan string, results chan<- result.Result, token string, leaseTimeout time.Duration, processedCount *int64) *Server {
+	s := &Server{
+		urls:           urls,
+		results:        results,
+		token:          token,
+		leaseTimeout:   leaseTimeout,
+		processedCount: processedCount,
+		outstanding:    make(map[string]time.Time),
+		done:           make(chan struct{}),
+	}
+	s.httpServer = &http.Server{Addr: addr, Handler: s.handler()}
+	return s
+}
+
+// Done is closed once all URLs were handed out and their results received
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// Listen binds the listen address, so an address in use fails the scan before agents are awaited
+func (s *Server) Listen() error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	return nil
+}
+
+// Serve serves the agent API on the address bound by Listen until Close is called
+func (s *Server) Serve() error {
+	err := s.httpServer.Serve(s.listener)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	// No agent reaches the server anymore, the scan ends with the results received so far
+	s.mu.Lock()
+	s.drained = true
+	s.outstanding = make(map[string]time.Time)
+	s.mu.Unlock()
+	s.finished()
+	return err
+}
+
+// Close keeps answering for a moment so polling agents learn that the scan is done, then stops the server
+func (s *Server) Close() error {
+	time.Sleep(2 * pollInterval)
+	return s.httpServer.Close()
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(batchPath, s.authorized(s.handleBatch))
+	mux.HandleFunc(resultsPath, s.authorized(s.handleResults))
+	return mux
+}
+
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(tokenHeader)), []byte(s.token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	size, err := strconv.Atoi(r.URL.Query().Get("size"))
+	if err != nil || size <= 0 {
+		size = defaultBatchSize
+	}
+	if size > maxBatchSize {
+		size = maxBatchSize
+	}
+
+	urls := s.nextURLs(size)
+	b := batch{URLs: urls, Done: len(urls) == 0 && s.finished()}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b)
+}
+
+func (s *Server) nextURLs(size int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var urls []string
+
+	// Expired leases first
+	for url, leased := range s.outstanding {
+		if len(urls) >= size {
+			return urls
+		}
+		if now.Sub(leased) > s.leaseTimeout {
+			s.outstanding[url] = now
+			urls = append(urls, url)
+		}
+	}
+
+	for len(urls) < size && !s.drained {
+		select {
+		case url, ok := <-s.urls:
+			if !ok {
+				s.drained = true
+				break
+			}
+			s.outstanding[url] = now
+			urls = append(urls, url)
+		default:
+			// Generation is slower than the agents, hand out what we have
+			return urls
+		}
+	}
+
+	return urls
+}
+
+func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var received []wireResult
+	body := http.MaxBytesReader(w, r.Body, maxResultsBodySize)
+	if err := json.NewDecoder(body).Decode(&received); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, wr := range received {
+		s.mu.Lock()
+		_, expected := s.outstanding[wr.URL]
+		delete(s.outstanding, wr.URL)
+		if expected {
+			s.sending++
+		}
+		s.mu.Unlock()
+
+		// Results for re-leased URLs may arrive twice, only the first one counts
+		if !expected {
+			continue
+		}
+
+		atomic.AddInt64(s.processedCount, 1)
+		s.results <- wr.toResult()
+
+		s.mu.Lock()
+		s.sending--
+		s.mu.Unlock()
+	}
+
+	s.finished()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// finished reports whether all URLs were processed and closes the done channel in that case
+func (s *Server) finished() bool {
+	s.mu.Lock()
+	finished := s.drained && len(s.outstanding) == 0 && s.sending == 0
+	s.mu.Unlock()
+
+	if finished {
+		s.closeOnce.Do(func() { close(s.done) })
+	}
+	return finished
+}
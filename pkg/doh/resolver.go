@@ -0,0 +1,283 @@
+// Package doh resolves hostnames with DNS-over-HTTPS (RFC 8484) instead of the system resolver, e.g.
+// on networks whose DNS filters or poisons the lookups of recon targets.
+package doh
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	typeA    = 1
+	typeAAAA = 28
+
+	// minTTL keeps records with a TTL of a few seconds and unknown hosts from causing a query per
+	// connection
+	minTTL = 30 * time.Second
+	// maxMessageSize is the maximum size of a DNS message
+	maxMessageSize = 65535
+)
+
+type cacheEntry struct {
+	done    chan struct{}
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// Resolver sends the A and AAAA queries of a hostname to a DoH endpoint and caches the addresses
+// for the TTL of the records
+type Resolver struct {
+	endpoint string
+	client   *http.Client
+	dialer   *net.Dialer
+
+	mu    sync.Mutex
+	cache map[string]*cacheEntry
+}
+
+// NewResolver returns a resolver for endpoint, e.g. https://1.1.1.1/dns-query. The host of an
+// endpoint given by name is resolved by the system resolver.
+func NewResolver(endpoint string, timeout time.Duration) (*Resolver, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return nil, fmt.Errorf("%s is not an http(s) URL", endpoint)
+	}
+	return &Resolver{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+		dialer:   &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		cache:    make(map[string]*cacheEntry),
+	}, nil
+}
+
+// LookupHost returns the IPv4 and IPv6 addresses of host, the IPv4 addresses first. Concurrent
+// lookups of a host share one query.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	r.mu.Lock()
+	entry, ok := r.cache[host]
+	// The expiry of entries which are still resolved is zero
+	if ok && !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		ok = false
+	}
+	if !ok {
+		entry = &cacheEntry{done: make(chan struct{})}
+		r.cache[host] = entry
+		r.mu.Unlock()
+
+		addrs, ttl, err := r.resolve(ctx, host)
+		if ttl < minTTL {
+			ttl = minTTL
+		}
+		r.mu.Lock()
+		entry.addrs, entry.err, entry.expires = addrs, err, time.Now().Add(ttl)
+		var dnsErr *net.DNSError
+		if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+			// Failed lookups are repeated by the next caller, unknown hosts are cached as well
+			delete(r.cache, host)
+		}
+		r.mu.Unlock()
+		close(entry.done)
+		return addrs, err
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-entry.done:
+		return entry.addrs, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// DialContext connects to address like net.Dialer does, but resolves its host with the resolver.
+// It is used as http.Transport.DialContext.
+func (r *Resolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return r.dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := r.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var firstErr error
+	for _, addr := range addrs {
+		conn, err := r.dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// resolve queries the A and AAAA records of host at once and returns the addresses and the
+// smallest TTL of them
+func (r *Resolver) resolve(ctx context.Context, host string) ([]string, time.Duration, error) {
+	var v4, v6 []string
+	var ttl4, ttl6 time.Duration
+	var err4, err6 error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		v4, ttl4, err4 = r.query(ctx, host, typeA)
+	}()
+	go func() {
+		defer wg.Done()
+		v6, ttl6, err6 = r.query(ctx, host, typeAAAA)
+	}()
+	wg.Wait()
+
+	addrs := append(v4, v6...)
+	if len(addrs) == 0 {
+		if err4 != nil {
+			return nil, 0, err4
+		}
+		if err6 != nil {
+			return nil, 0, err6
+		}
+		return nil, 0, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	ttl := ttl4
+	if len(v4) == 0 || (len(v6) > 0 && ttl6 < ttl) {
+		ttl = ttl6
+	}
+	return addrs, ttl, nil
+}
+
+// query sends the question for the records of qtype of host with GET
+func (r *Resolver) query(ctx context.Context, host string, qtype uint16) ([]string, time.Duration, error) {
+	msg, err := buildQuery(host, qtype)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.endpoint+"?dns="+base64.RawURLEncoding.EncodeToString(msg), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("DoH query for %s: %w", host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("DoH query for %s: %s answered %d", host, r.endpoint, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMessageSize))
+	if err != nil {
+		return nil, 0, fmt.Errorf("DoH query for %s: %w", host, err)
+	}
+	return parseAnswer(host, body, qtype)
+}
+
+// buildQuery returns the DNS message asking recursively for the records of qtype of host. The ID
+// is 0 as recommended by RFC 8484, so the answers can be cached by HTTP caches.
+func buildQuery(host string, qtype uint16) ([]byte, error) {
+	msg := []byte{0, 0, 1, 0, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range strings.Split(host, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid hostname %s", host)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	return binary.BigEndian.AppendUint16(msg, 1), nil
+}
+
+var errMalformed = errors.New("malformed DoH answer")
+
+// parseAnswer returns the addresses of the records of qtype in msg, those of a CNAME chain
+// included, and their smallest TTL
+func parseAnswer(host string, msg []byte, qtype uint16) ([]string, time.Duration, error) {
+	if len(msg) < 12 {
+		return nil, 0, errMalformed
+	}
+	switch rcode := msg[3] & 0x0f; rcode {
+	case 0:
+	case 3:
+		return nil, 0, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	default:
+		return nil, 0, fmt.Errorf("DoH query for %s failed with rcode %d", host, rcode)
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:6]))
+	answers := int(binary.BigEndian.Uint16(msg[6:8]))
+
+	offset := 12
+	var ok bool
+	for i := 0; i < questions; i++ {
+		if offset, ok = skipName(msg, offset); !ok || offset+4 > len(msg) {
+			return nil, 0, errMalformed
+		}
+		offset += 4
+	}
+
+	var addrs []string
+	var ttl time.Duration
+	for i := 0; i < answers; i++ {
+		if offset, ok = skipName(msg, offset); !ok || offset+10 > len(msg) {
+			return nil, 0, errMalformed
+		}
+		rtype := binary.BigEndian.Uint16(msg[offset:])
+		recordTTL := time.Duration(binary.BigEndian.Uint32(msg[offset+4:])) * time.Second
+		length := int(binary.BigEndian.Uint16(msg[offset+8:]))
+		offset += 10
+		if offset+length > len(msg) {
+			return nil, 0, errMalformed
+		}
+		data := msg[offset : offset+length]
+		offset += length
+
+		if rtype != qtype || (rtype == typeA && length != net.IPv4len) || (rtype == typeAAAA && length != net.IPv6len) {
+			continue
+		}
+		addrs = append(addrs, net.IP(data).String())
+		if len(addrs) == 1 || recordTTL < ttl {
+			ttl = recordTTL
+		}
+	}
+	return addrs, ttl, nil
+}
+
+// skipName returns the offset after the possibly compressed name at offset
+func skipName(msg []byte, offset int) (int, bool) {
+	for offset < len(msg) {
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			return offset + 1, true
+		case length&0xc0 == 0xc0:
+			return offset + 2, offset+2 <= len(msg)
+		default:
+			offset += 1 + length
+		}
+	}
+	return 0, false
+}
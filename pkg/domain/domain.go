@@ -1,9 +1,14 @@
 package domain
 
 import (
+	"bufio"
 	"fmt"
 	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/request"
 	"github.com/dsecuredcom/dynamic-file-searcher/pkg/utils"
+	"io"
+	"log"
+	"net"
 	"regexp"
 	"strconv"
 	"strings"
@@ -12,6 +17,8 @@ import (
 type domainProtocol struct {
 	domain   string
 	protocol string
+	// host is the domain without the port of the matrix, the words are derived from it
+	host string
 }
 
 var (
@@ -233,9 +240,12 @@ func makeUniqueList(input []string) []string {
 	return list
 }
 
-func GetDomains(domainsFile, singleDomain string) []string {
+func GetDomains(domainsFile, singleDomain string) ([]string, error) {
 	if domainsFile != "" {
-		allLines := utils.ReadLines(domainsFile)
+		allLines, err := utils.ReadLines(domainsFile)
+		if err != nil {
+			return nil, err
+		}
 		var validDomains []string
 		for _, line := range allLines {
 			trimmedLine := strings.TrimSpace(line)
@@ -244,36 +254,37 @@ func GetDomains(domainsFile, singleDomain string) []string {
 			}
 		}
 		validDomains = utils.ShuffleStrings(validDomains)
-		return validDomains
+		return validDomains, nil
 	}
-	return []string{singleDomain}
+	return []string{singleDomain}, nil
 }
 
-func GenerateURLs(domains, paths []string, cfg *config.Config) ([]string, int) {
-	var domainProtocols []domainProtocol
-
-	var proto = "https"
+// StreamDomains sends every not excluded domain line of r to out as soon as it is read and closes out at EOF.
+// The tags of the lines are recorded in tags.
+func StreamDomains(r io.Reader, out chan<- string, exclude *ExcludeFilter, tags *Tags) {
+	defer close(out)
 
-	for _, d := range domains {
-		if cfg.ForceHTTPProt {
-			proto = "http"
-		} else {
-			proto = "https"
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		trimmedLine := strings.TrimSpace(scanner.Text())
+		if trimmedLine == "" || strings.HasPrefix(trimmedLine, "#") {
+			continue
 		}
-
-		if strings.HasPrefix(d, "http://") {
-			proto = "http"
-			d = strings.TrimPrefix(d, "http://")
+		if trimmedLine = tags.Strip(trimmedLine); !exclude.Excluded(trimmedLine) {
+			out <- trimmedLine
 		}
+	}
 
-		if strings.HasPrefix(d, "https://") {
-			proto = "https"
-			d = strings.TrimPrefix(d, "https://")
-		}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error reading domains from stdin: %v\n", err)
+	}
+}
 
-		d = strings.TrimSuffix(d, "/")
+func GenerateURLs(domains, paths []string, cfg *config.Config) ([]string, int) {
+	var domainProtocols []domainProtocol
 
-		domainProtocols = append(domainProtocols, domainProtocol{domain: d, protocol: proto})
+	for _, d := range domains {
+		domainProtocols = append(domainProtocols, expandTargets(d, cfg)...)
 	}
 
 	var allURLs []string
@@ -294,7 +305,7 @@ func GenerateURLs(domains, paths []string, cfg *config.Config) ([]string, int) {
 				continue
 			}
 
-			words := splitDomain(dp.domain, cfg)
+			words := Words(dp.host, cfg)
 
 			if len(cfg.BasePaths) == 0 {
 				for _, word := range words {
@@ -310,11 +321,85 @@ func GenerateURLs(domains, paths []string, cfg *config.Config) ([]string, int) {
 		}
 	}
 
+	if len(cfg.APITemplates) > 0 {
+		allURLs = append(allURLs, request.TemplateURLs(allURLs, len(cfg.APITemplates))...)
+	}
+
 	allURLs = utils.ShuffleStrings(allURLs)
 
 	return allURLs, len(domainProtocols)
 }
 
+// expandTargets returns the base URLs of a domain input line. Lines with a scheme are used as they
+// are, lines with a port are requested with every scheme of cfg.Matrix on that port and all
+// other lines with every combination of cfg.Matrix. With cfg.WWWVariants the www./non-www.
+// counterpart of the host is added.
+func expandTargets(line string, cfg *config.Config) []domainProtocol {
+	matrix := cfg.Matrix
+	if len(matrix) == 0 {
+		matrix = []config.Target{{Scheme: "https", Port: 443}}
+		if cfg.ForceHTTPProt {
+			matrix = []config.Target{{Scheme: "http", Port: 80}}
+		}
+	}
+
+	proto, base := splitBaseURL(line, matrix[0].Scheme == "http")
+	host, path := base, ""
+	if i := strings.IndexByte(base, '/'); i >= 0 {
+		host, path = base[:i], base[i:]
+	}
+	hosts := []string{host}
+	if cfg.WWWVariants {
+		hosts = wwwVariants(host)
+	}
+
+	var targets []domainProtocol
+	seen := make(map[string]bool)
+	for _, variant := range hosts {
+		variantBase := variant + path
+		if strings.Contains(line, "://") {
+			targets = append(targets, domainProtocol{domain: variantBase, protocol: proto, host: variantBase})
+			continue
+		}
+
+		_, _, err := net.SplitHostPort(variant)
+		hasPort := err == nil
+		for _, target := range matrix {
+			dp := domainProtocol{domain: target.Host(variant) + path, protocol: target.Scheme, host: variantBase}
+			if hasPort {
+				// The port of the line is kept, only the schemes of the matrix are used
+				dp.domain = variantBase
+			}
+			if key := dp.protocol + "://" + dp.domain; !seen[key] {
+				seen[key] = true
+				targets = append(targets, dp)
+			}
+		}
+	}
+	return targets
+}
+
+// splitBaseURL splits a domain input line into its scheme and the rest of the base URL: host, port
+// and base path of lines like https://host:8443/app/, which prefix all generated URLs. Lines
+// without scheme use https (http with forceHTTP), query and fragment are dropped.
+func splitBaseURL(line string, forceHTTP bool) (scheme, base string) {
+	scheme = "https"
+	if forceHTTP {
+		scheme = "http"
+	}
+
+	if i := strings.Index(line, "://"); i >= 0 {
+		if lineScheme := strings.ToLower(line[:i]); lineScheme == "http" || lineScheme == "https" {
+			scheme = lineScheme
+		}
+		line = line[i+3:]
+	}
+	if i := strings.IndexAny(line, "?#"); i >= 0 {
+		line = line[:i]
+	}
+	return scheme, strings.TrimRight(line, "/")
+}
+
 func removeTLD(host string) string {
 	host = strings.ToLower(host)
 	parts := strings.Split(host, ".")
@@ -330,3 +415,35 @@ func removeTLD(host string) string {
 
 	return host
 }
+
+// Words returns the words the path generation derives from host, including the extra words of
+// cfg.HostWords (e.g. from the page title found by httpx)
+func Words(host string, cfg *config.Config) []string {
+	words := splitDomain(host, cfg)
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://"), "/")
+	if hostWords := cfg.HostWords[trimmed]; len(hostWords) > 0 {
+		words = makeUniqueList(append(words, hostWords...))
+	}
+	return words
+}
+
+// Apex returns the registrable domain of host, e.g. example.co.uk for api.dev.example.co.uk:8443.
+// IP addresses and hosts without a known TLD are returned unchanged (without the port).
+func Apex(host string) string {
+	host = strings.ToLower(strings.Split(host, ":")[0])
+	if ipv4Regex.MatchString(host) {
+		return host
+	}
+
+	parts := strings.Split(host, ".")
+	for i := 1; i < len(parts); i++ {
+		potentialTLD := strings.Join(parts[i:], ".")
+		for _, tld := range commonTLDs {
+			if potentialTLD == tld {
+				return strings.Join(parts[i-1:], ".")
+			}
+		}
+	}
+
+	return host
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/utils"
+)
+
+// ExcludeFilter drops out-of-scope hosts from the input. A nil filter excludes nothing.
+type ExcludeFilter struct {
+	hosts    map[string]bool
+	suffixes []string
+	regex    *regexp.Regexp
+}
+
+// NewExcludeFilter reads the hosts to exclude from excludeFile (one per line, *.example.com also
+// excludes all subdomains) and combines them with regex. It returns nil if both are empty.
+func NewExcludeFilter(excludeFile string, regex *regexp.Regexp) (*ExcludeFilter, error) {
+	if excludeFile == "" && regex == nil {
+		return nil, nil
+	}
+
+	filter := &ExcludeFilter{
+		hosts: make(map[string]bool),
+		regex: regex,
+	}
+
+	if excludeFile != "" {
+		lines, err := utils.ReadLines(excludeFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			line = strings.ToLower(strings.TrimSpace(line))
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if strings.HasPrefix(line, "*.") {
+				filter.suffixes = append(filter.suffixes, strings.TrimPrefix(line, "*"))
+				continue
+			}
+			filter.hosts[hostOnly(line)] = true
+		}
+	}
+
+	return filter, nil
+}
+
+// Excluded reports whether the host of the domain input line is out of scope
+func (f *ExcludeFilter) Excluded(domain string) bool {
+	if f == nil {
+		return false
+	}
+
+	host := hostOnly(strings.ToLower(domain))
+
+	if f.hosts[host] {
+		return true
+	}
+
+	for _, suffix := range f.suffixes {
+		if strings.HasSuffix(host, suffix) || host == strings.TrimPrefix(suffix, ".") {
+			return true
+		}
+	}
+
+	return f.regex != nil && f.regex.MatchString(host)
+}
+
+// Filter returns all domains which are not excluded
+func (f *ExcludeFilter) Filter(domains []string) []string {
+	if f == nil {
+		return domains
+	}
+
+	var filtered []string
+	for _, d := range domains {
+		if !f.Excluded(d) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// hostOnly strips scheme, path and port from a domain input line
+func hostOnly(domain string) string {
+	domain = strings.TrimPrefix(domain, "http://")
+	domain = strings.TrimPrefix(domain, "https://")
+	domain = strings.Split(domain, "/")[0]
+	return strings.Split(domain, ":")[0]
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/utils"
+)
+
+type pathsMapping struct {
+	// host is matched exactly, suffix (".example.com" of "*.example.com") matches the subdomains
+	host   string
+	suffix string
+	paths  []string
+}
+
+// PathsMap maps host patterns to tailored wordlists, e.g. "*.wordpress-host.com wp-paths.txt".
+// A nil map maps nothing.
+type PathsMap struct {
+	mappings []pathsMapping
+}
+
+// NewPathsMap reads the mappings of filename, one "<pattern> <paths file>" per line. Relative
+// paths files are resolved relative to the directory of filename.
+func NewPathsMap(filename string) (*PathsMap, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	m := &PathsMap{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected '<pattern> <paths file>'", filename, lineNumber)
+		}
+
+		pathsFile := fields[1]
+		if !filepath.IsAbs(pathsFile) {
+			pathsFile = filepath.Join(filepath.Dir(filename), pathsFile)
+		}
+		paths, err := utils.ReadLines(pathsFile)
+		if err != nil {
+			return nil, err
+		}
+		mapping := pathsMapping{paths: utils.UniqueStrings(paths)}
+		pattern := strings.ToLower(fields[0])
+		if strings.HasPrefix(pattern, "*.") {
+			mapping.suffix = strings.TrimPrefix(pattern, "*")
+		} else {
+			mapping.host = hostOnly(pattern)
+		}
+		m.mappings = append(m.mappings, mapping)
+	}
+	return m, scanner.Err()
+}
+
+// Paths returns the paths of all patterns matching the host of the domain input line. ok is false
+// if no pattern matches.
+func (m *PathsMap) Paths(domain string) (paths []string, ok bool) {
+	if m == nil {
+		return nil, false
+	}
+
+	host := hostOnly(strings.ToLower(domain))
+	for _, mapping := range m.mappings {
+		if host == mapping.host || (mapping.suffix != "" && (strings.HasSuffix(host, mapping.suffix) || host == mapping.suffix[1:])) {
+			paths = append(paths, mapping.paths...)
+			ok = true
+		}
+	}
+	if ok {
+		paths = utils.UniqueStrings(paths)
+	}
+	return paths, ok
+}
+
+// Len returns the number of mappings
+func (m *PathsMap) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.mappings)
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/utils"
+)
+
+// Tags holds the tags of input lines like "example.com,program-a;owner-b" per host, so every
+// result of a host can be attributed to its program or asset owner
+type Tags struct {
+	mu     sync.RWMutex
+	byHost map[string][]string
+}
+
+func NewTags() *Tags {
+	return &Tags{byHost: make(map[string][]string)}
+}
+
+// Load reads the tags of a companion file whose lines have the same format as tagged input lines
+func (t *Tags) Load(filename string) error {
+	lines, err := utils.ReadLines(filename)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			t.Strip(line)
+		}
+	}
+	return nil
+}
+
+// Strip records the tags of an input line for its host and returns the line without them
+func (t *Tags) Strip(line string) string {
+	d, tagList, found := strings.Cut(line, ",")
+	d = strings.TrimSpace(d)
+	if !found {
+		return d
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(tagList, ";") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > 0 {
+		host := strings.ToLower(hostOnly(d))
+		t.mu.Lock()
+		t.byHost[host] = utils.UniqueStrings(append(t.byHost[host], tags...))
+		t.mu.Unlock()
+	}
+	return d
+}
+
+// Lookup returns the tags of the host of rawURL
+func (t *Tags) Lookup(rawURL string) []string {
+	if t == nil {
+		return nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.byHost[strings.ToLower(parsed.Hostname())]
+}
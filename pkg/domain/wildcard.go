@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+)
+
+// dnsWildcardWorkers is the number of domains resolved at the same time
+const dnsWildcardWorkers = 20
+
+type parentWildcard struct {
+	once sync.Once
+	// addrs are the addresses of a random label of the parent domain, empty without wildcard record
+	addrs string
+}
+
+// DNSWildcards drops hosts which only resolve because of a wildcard DNS record of their parent
+// domain, e.g. generated subdomains of a catch-all zone. A nil *DNSWildcards drops nothing.
+type DNSWildcards struct {
+	verbose bool
+
+	mu      sync.Mutex
+	parents map[string]*parentWildcard
+	skipped map[string]bool
+}
+
+func NewDNSWildcards(verbose bool) *DNSWildcards {
+	return &DNSWildcards{
+		verbose: verbose,
+		parents: make(map[string]*parentWildcard),
+		skipped: make(map[string]bool),
+	}
+}
+
+// Filter returns a channel with the domains of domains which are not wildcard hosts. It is closed
+// once domains is closed and all domains are resolved.
+func (w *DNSWildcards) Filter(domains <-chan string) <-chan string {
+	if w == nil {
+		return domains
+	}
+
+	filtered := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < dnsWildcardWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range domains {
+				if !w.Wildcard(d) {
+					filtered <- d
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(filtered)
+	}()
+	return filtered
+}
+
+// Wildcard reports whether the host of the domain line d resolves to the same addresses as a
+// random label of its parent domain
+func (w *DNSWildcards) Wildcard(d string) bool {
+	if w == nil {
+		return false
+	}
+	host := strings.ToLower(hostOnly(d))
+	dot := strings.IndexByte(host, '.')
+	// Apex domains have no parent to compare with
+	if net.ParseIP(host) != nil || dot < 0 || !strings.Contains(host[dot+1:], ".") {
+		return false
+	}
+	parent := host[dot+1:]
+
+	wildcard := w.parentAddresses(parent)
+	if wildcard == "" || lookupAddresses(host) != wildcard {
+		return false
+	}
+
+	w.mu.Lock()
+	w.skipped[host] = true
+	w.mu.Unlock()
+	if w.verbose {
+		log.Printf("Skipped %s, it resolves to the wildcard DNS record of %s\n", host, parent)
+	}
+	return true
+}
+
+// Skipped returns the number of hosts dropped as wildcard hosts
+func (w *DNSWildcards) Skipped() int {
+	if w == nil {
+		return 0
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.skipped)
+}
+
+// parentAddresses resolves a random label of parent once and returns its addresses
+func (w *DNSWildcards) parentAddresses(parent string) string {
+	w.mu.Lock()
+	p, ok := w.parents[parent]
+	if !ok {
+		p = &parentWildcard{}
+		w.parents[parent] = p
+	}
+	w.mu.Unlock()
+
+	p.once.Do(func() {
+		p.addrs = lookupAddresses(randomLabel() + "." + parent)
+	})
+	return p.addrs
+}
+
+func randomLabel() string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+	label := make([]byte, 16)
+	for i := range label {
+		label[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(label)
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/doh"
+)
+
+const wwwLookupTimeout = 5 * time.Second
+
+// wwwCache holds the variants per host, the URLs of a host are generated once per path group
+var wwwCache sync.Map
+
+// wwwVariants returns host (which may have a port) and its www./non-www. counterpart. The
+// counterpart is left out if it does not resolve or resolves to the same addresses as host, as both
+// serve the same content then in all likelihood. IP addresses have no counterpart.
+func wwwVariants(host string) []string {
+	if cached, ok := wwwCache.Load(host); ok {
+		return cached.([]string)
+	}
+
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, ""
+	}
+	if net.ParseIP(strings.Trim(name, "[]")) != nil {
+		return []string{host}
+	}
+
+	counterpart := "www." + name
+	if strings.HasPrefix(strings.ToLower(name), "www.") {
+		counterpart = name[len("www."):]
+	}
+
+	variants := []string{host}
+	counterpartAddrs := lookupAddresses(counterpart)
+	if len(counterpartAddrs) > 0 && counterpartAddrs != lookupAddresses(name) {
+		if port != "" {
+			counterpart = net.JoinHostPort(counterpart, port)
+		}
+		variants = append(variants, counterpart)
+	}
+
+	wwwCache.Store(host, variants)
+	return variants
+}
+
+// resolver resolves the hosts of the DNS checks, replaced by SetResolver
+var resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+} = net.DefaultResolver
+
+// SetResolver makes -www-variants and -dns-wildcard resolve the hosts with the -doh resolver
+func SetResolver(r *doh.Resolver) {
+	resolver = r
+}
+
+// lookupAddresses returns the sorted addresses of name joined by commas, empty if it does not resolve
+func lookupAddresses(name string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), wwwLookupTimeout)
+	defer cancel()
+
+	addrs, err := resolver.LookupHost(ctx, name)
+	if err != nil {
+		return ""
+	}
+	sort.Strings(addrs)
+	return strings.Join(addrs, ",")
+}
@@ -0,0 +1,165 @@
+// Package endpoints discovers paths referenced by the JavaScript of a host, so they can be requested
+// in addition to the configured paths.
+package endpoints
+
+import (
+	"log"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+// maxScriptsPerHost bounds the requests of a single host, bundlers often split into many chunks
+const maxScriptsPerHost = 25
+
+var (
+	scriptSrcRegex = regexp.MustCompile(`(?i)<script[^>]+src\s*=\s*["']([^"']+)["']`)
+	// Quoted absolute paths like "/api/v1/users" or relative paths with an extension like "static/app.js"
+	quotedPathRegex = regexp.MustCompile("[\"'`](/[a-zA-Z0-9_\\-.~/]{2,200}|[a-zA-Z0-9_\\-]+/[a-zA-Z0-9_\\-./]{1,200}\\.[a-zA-Z0-9]{1,6})(?:[?#][^\"'`]*)?[\"'`]")
+)
+
+// Assets are no endpoints worth requesting
+var ignoredExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".ico": true, ".webp": true,
+	".css": true, ".woff": true, ".woff2": true, ".ttf": true, ".eot": true, ".mp4": true, ".mp3": true,
+}
+
+type Client interface {
+	MakeRequest(url string) result.Result
+}
+
+// Crawler fetches the root page of a host and the scripts it references and extracts path-like strings
+// from them. Scripts referenced by scripts are followed up to depth levels below the root page.
+type Crawler struct {
+	client    Client
+	depth     int
+	forceHTTP bool
+	verbose   bool
+}
+
+func NewCrawler(client Client, depth int, forceHTTP, verbose bool) *Crawler {
+	return &Crawler{client: client, depth: depth, forceHTTP: forceHTTP, verbose: verbose}
+}
+
+type page struct {
+	url   string
+	depth int
+}
+
+// Paths returns the paths found in the scripts of host, without the leading slash
+func (c *Crawler) Paths(host string) []string {
+	root, err := url.Parse(c.rootURL(host))
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	seenPaths := make(map[string]bool)
+	seenPages := map[string]bool{root.String(): true}
+	queue := []page{{url: root.String()}}
+
+	for len(queue) > 0 && len(seenPages) <= maxScriptsPerHost+1 {
+		current := queue[0]
+		queue = queue[1:]
+
+		res := c.client.MakeRequest(current.url)
+		if res.Error != nil || res.StatusCode != 200 {
+			continue
+		}
+
+		var references []string
+		if IsScript(res) {
+			for _, path := range Extract(res.Content) {
+				if !seenPaths[path] {
+					seenPaths[path] = true
+					paths = append(paths, path)
+				}
+				if isScriptPath(path) {
+					references = append(references, "/"+path)
+				}
+			}
+		} else {
+			references = Scripts(res.Content)
+		}
+
+		if current.depth >= c.depth {
+			continue
+		}
+		base, _ := url.Parse(current.url)
+		for _, reference := range references {
+			ref, err := url.Parse(reference)
+			if err != nil {
+				continue
+			}
+			script := base.ResolveReference(ref)
+			// Scripts of CDNs and third parties say nothing about the host
+			if script.Hostname() != root.Hostname() || seenPages[script.String()] {
+				continue
+			}
+			seenPages[script.String()] = true
+			queue = append(queue, page{url: script.String(), depth: current.depth + 1})
+		}
+	}
+
+	if c.verbose {
+		log.Printf("JS discovery: %d paths from %d pages of %s\n", len(paths), len(seenPages), root.Host)
+	}
+	return paths
+}
+
+func (c *Crawler) rootURL(host string) string {
+	host = strings.TrimSuffix(host, "/")
+	if strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://") {
+		return host + "/"
+	}
+	if c.forceHTTP {
+		return "http://" + host + "/"
+	}
+	return "https://" + host + "/"
+}
+
+// IsScript reports whether res is a JavaScript file
+func IsScript(res result.Result) bool {
+	if strings.Contains(strings.ToLower(res.ContentType), "javascript") {
+		return true
+	}
+	parsed, err := url.Parse(res.URL)
+	return err == nil && isScriptPath(parsed.Path)
+}
+
+func isScriptPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".js" || ext == ".mjs"
+}
+
+// Scripts returns the src attributes of the script tags of an HTML page
+func Scripts(html string) []string {
+	var scripts []string
+	for _, match := range scriptSrcRegex.FindAllStringSubmatch(html, -1) {
+		scripts = append(scripts, match[1])
+	}
+	return scripts
+}
+
+// Extract returns the path-like strings of a script without the leading slash, query and fragment
+func Extract(script string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, match := range quotedPathRegex.FindAllStringSubmatch(script, -1) {
+		path := match[1]
+		// Protocol-relative URLs point to other hosts, comments and regular expressions aren't paths
+		if strings.HasPrefix(path, "//") || strings.Contains(path, "/*") {
+			continue
+		}
+		path = strings.Trim(path, "/")
+		if path == "" || seen[path] || ignoredExtensions[strings.ToLower(filepath.Ext(path))] {
+			continue
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	return paths
+}
@@ -0,0 +1,93 @@
+package entropy
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxTokenLength = 256
+	maxFindings    = 5
+)
+
+var (
+	tokenRegex = regexp.MustCompile(`[A-Za-z0-9+/=_\-]+`)
+	// Inline images are the most common source of long high-entropy strings in web responses
+	dataImageRegex = regexp.MustCompile(`data:image/[a-zA-Z0-9.+\-]+;base64,[A-Za-z0-9+/=]+`)
+	// Base64 encoded image headers (png, jpeg, gif, webp, svg, ico)
+	imagePrefixes = []string{"iVBORw0KGgo", "/9j/", "R0lGOD", "UklGR", "PHN2Zy", "AAABAA"}
+)
+
+// FindSecrets returns up to a handful of tokens from content which are at least minLength characters
+// long and whose Shannon entropy (bits per character) is at least threshold.
+func FindSecrets(content string, threshold float64, minLength int) []string {
+	content = dataImageRegex.ReplaceAllString(content, "")
+
+	var findings []string
+	seen := make(map[string]bool)
+
+	for _, token := range tokenRegex.FindAllString(content, -1) {
+		if len(token) < minLength || len(token) > maxTokenLength || seen[token] {
+			continue
+		}
+		seen[token] = true
+
+		if isImageData(token) || !hasMixedCharacters(token) {
+			continue
+		}
+
+		if Shannon(token) >= threshold {
+			findings = append(findings, token)
+			if len(findings) >= maxFindings {
+				break
+			}
+		}
+	}
+
+	return findings
+}
+
+// Shannon calculates the Shannon entropy of s in bits per character.
+func Shannon(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+
+	length := float64(len(s))
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
+
+func isImageData(token string) bool {
+	for _, prefix := range imagePrefixes {
+		if strings.HasPrefix(token, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Real keys mix letters and digits, this filters out long words, paths and css class lists
+func hasMixedCharacters(token string) bool {
+	hasLetter, hasDigit := false, false
+	for _, r := range token {
+		switch {
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			hasLetter = true
+		}
+	}
+	return hasLetter && hasDigit
+}
@@ -0,0 +1,176 @@
+// Package expr evaluates the boolean expressions of -match-expr against a response, e.g.
+// status == 200 && size > 1024 && ct contains "octet" && body contains "PK".
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Env holds the values of the variables of a response
+type Env struct {
+	Status     int
+	Size       int64
+	DurationMs int64
+	// ContentType is lowercased, so comparisons of ct are case-insensitive
+	ContentType string
+	Body        string
+	Title       string
+	Server      string
+	FileType    string
+	URL         string
+	Path        string
+}
+
+type valueType int
+
+const (
+	typeNumber valueType = iota
+	typeString
+	typeBool
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeNumber:
+		return "number"
+	case typeString:
+		return "string"
+	default:
+		return "condition"
+	}
+}
+
+// variables maps the names usable in expressions to their type and value
+var variables = map[string]struct {
+	typ   valueType
+	value func(Env) value
+}{
+	"status":   {typeNumber, func(e Env) value { return value{num: float64(e.Status)} }},
+	"size":     {typeNumber, func(e Env) value { return value{num: float64(e.Size)} }},
+	"duration": {typeNumber, func(e Env) value { return value{num: float64(e.DurationMs)} }},
+	"ct":       {typeString, func(e Env) value { return value{str: e.ContentType} }},
+	"body":     {typeString, func(e Env) value { return value{str: e.Body} }},
+	"title":    {typeString, func(e Env) value { return value{str: e.Title} }},
+	"server":   {typeString, func(e Env) value { return value{str: e.Server} }},
+	"filetype": {typeString, func(e Env) value { return value{str: e.FileType} }},
+	"url":      {typeString, func(e Env) value { return value{str: e.URL} }},
+	"path":     {typeString, func(e Env) value { return value{str: e.Path} }},
+}
+
+type value struct {
+	num     float64
+	str     string
+	boolean bool
+}
+
+type nodeKind int
+
+const (
+	kindLiteral nodeKind = iota
+	kindVariable
+	kindNot
+	kindAnd
+	kindOr
+	kindCompare
+	kindMatches
+)
+
+// node is an element of the syntax tree, its type is checked while parsing
+type node struct {
+	kind        nodeKind
+	typ         valueType
+	literal     value
+	variable    string
+	op          string
+	re          *regexp.Regexp
+	left, right *node
+}
+
+func (n *node) eval(env Env) value {
+	switch n.kind {
+	case kindLiteral:
+		return n.literal
+	case kindVariable:
+		return variables[n.variable].value(env)
+	case kindNot:
+		return value{boolean: !n.left.eval(env).boolean}
+	case kindAnd:
+		return value{boolean: n.left.eval(env).boolean && n.right.eval(env).boolean}
+	case kindOr:
+		return value{boolean: n.left.eval(env).boolean || n.right.eval(env).boolean}
+	case kindMatches:
+		return value{boolean: n.re.MatchString(n.left.eval(env).str)}
+	default:
+		return value{boolean: compare(n.op, n.left.typ, n.left.eval(env), n.right.eval(env))}
+	}
+}
+
+func compare(op string, typ valueType, a, b value) bool {
+	if typ == typeNumber {
+		switch op {
+		case "==":
+			return a.num == b.num
+		case "!=":
+			return a.num != b.num
+		case "<":
+			return a.num < b.num
+		case "<=":
+			return a.num <= b.num
+		case ">":
+			return a.num > b.num
+		default:
+			return a.num >= b.num
+		}
+	}
+
+	switch op {
+	case "==":
+		return a.str == b.str
+	case "!=":
+		return a.str != b.str
+	case "contains":
+		return strings.Contains(a.str, b.str)
+	case "startswith":
+		return strings.HasPrefix(a.str, b.str)
+	default:
+		return strings.HasSuffix(a.str, b.str)
+	}
+}
+
+// Expr is a compiled expression
+type Expr struct {
+	source string
+	root   *node
+}
+
+// Compile parses source, which has to be a condition
+func Compile(source string) (*Expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos+1)
+	}
+	if root.typ != typeBool {
+		return nil, fmt.Errorf("the expression is a %s, not a condition", root.typ)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+// Match evaluates the expression for env
+func (e *Expr) Match(env Env) bool {
+	return e.root.eval(env).boolean
+}
+
+func (e *Expr) String() string {
+	return e.source
+}
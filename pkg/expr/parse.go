@@ -0,0 +1,250 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// value is the unquoted string or the number with its unit applied
+	value value
+	pos   int
+}
+
+// comparisons maps the comparison operators to the operand type they apply to
+var comparisons = map[string]valueType{
+	"==": typeNumber, "!=": typeNumber, "<": typeNumber, "<=": typeNumber, ">": typeNumber, ">=": typeNumber,
+	"contains": typeString, "startswith": typeString, "endswith": typeString, "matches": typeString,
+}
+
+var sizeUnits = map[string]float64{"": 1, "b": 1, "kb": 1 << 10, "mb": 1 << 20, "gb": 1 << 30}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(source) && source[end] != c {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			text := source[i : end+1]
+			unquoted := text[1 : len(text)-1]
+			if c == '"' {
+				var err error
+				if unquoted, err = strconv.Unquote(text); err != nil {
+					return nil, fmt.Errorf("invalid string %s at position %d", text, i+1)
+				}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, value: value{str: unquoted}, pos: i})
+			i = end + 1
+		case c >= '0' && c <= '9':
+			end := i
+			for end < len(source) && (source[end] >= '0' && source[end] <= '9' || source[end] == '.') {
+				end++
+			}
+			unitEnd := end
+			for unitEnd < len(source) && isLetter(source[unitEnd]) {
+				unitEnd++
+			}
+			number, err := strconv.ParseFloat(source[i:end], 64)
+			unit, known := sizeUnits[strings.ToLower(source[end:unitEnd])]
+			if err != nil || !known {
+				return nil, fmt.Errorf("invalid number '%s' at position %d", source[i:unitEnd], i+1)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[i:unitEnd], value: value{num: number * unit}, pos: i})
+			i = unitEnd
+		case isLetter(c):
+			end := i
+			for end < len(source) && (isLetter(source[end]) || source[end] >= '0' && source[end] <= '9') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: strings.ToLower(source[i:end]), pos: i})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(source[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected '%c' at position %d", c, i+1)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// parser is a recursive descent parser, || binds weaker than &&, which binds weaker than !
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek(text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind != tokenString && p.tokens[p.pos].text == text
+}
+
+func (p *parser) parseOr() (*node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical(kindOr, "||", left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (*node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("&&") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical(kindAnd, "&&", left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func logical(kind nodeKind, op string, left, right *node) (*node, error) {
+	if left.typ != typeBool || right.typ != typeBool {
+		return nil, fmt.Errorf("both sides of %s have to be conditions", op)
+	}
+	return &node{kind: kind, typ: typeBool, left: left, right: right}, nil
+}
+
+func (p *parser) parseUnary() (*node, error) {
+	if p.peek("!") {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if operand.typ != typeBool {
+			return nil, fmt.Errorf("! has to be followed by a condition")
+		}
+		return &node{kind: kindNot, typ: typeBool, left: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (*node, error) {
+	if p.peek("(") {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+		return inner, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.tokens) {
+		return left, nil
+	}
+	op := p.tokens[p.pos]
+	operandType, ok := comparisons[op.text]
+	if !ok || op.kind == tokenString || op.kind == tokenNumber {
+		return left, nil
+	}
+	p.pos++
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if left.typ != right.typ || (operandType == typeString && left.typ != typeString) || left.typ == typeBool {
+		return nil, fmt.Errorf("%s cannot compare a %s with a %s at position %d", op.text, left.typ, right.typ, op.pos+1)
+	}
+	if left.typ == typeString && operandType == typeNumber && op.text != "==" && op.text != "!=" {
+		return nil, fmt.Errorf("%s cannot compare strings at position %d", op.text, op.pos+1)
+	}
+
+	if op.text == "matches" {
+		if right.kind != kindLiteral {
+			return nil, fmt.Errorf("matches expects a string with a regular expression at position %d", op.pos+1)
+		}
+		re, err := regexp.Compile(right.literal.str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return &node{kind: kindMatches, typ: typeBool, left: left, re: re}, nil
+	}
+	return &node{kind: kindCompare, typ: typeBool, op: op.text, left: left, right: right}, nil
+}
+
+func (p *parser) parseOperand() (*node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of the expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch t.kind {
+	case tokenNumber:
+		return &node{kind: kindLiteral, typ: typeNumber, literal: t.value}, nil
+	case tokenString:
+		return &node{kind: kindLiteral, typ: typeString, literal: t.value}, nil
+	case tokenIdent:
+		if t.text == "true" || t.text == "false" {
+			return &node{kind: kindLiteral, typ: typeBool, literal: value{boolean: t.text == "true"}}, nil
+		}
+		if v, ok := variables[t.text]; ok {
+			return &node{kind: kindVariable, typ: v.typ, variable: t.text}, nil
+		}
+		return nil, fmt.Errorf("unknown variable '%s' at position %d", t.text, t.pos+1)
+	}
+	return nil, fmt.Errorf("unexpected '%s' at position %d", t.text, t.pos+1)
+}
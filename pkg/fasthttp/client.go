@@ -2,47 +2,126 @@ package fasthttp
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/bufpool"
 	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/control"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/hosts"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/request"
 	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
 	"github.com/valyala/fasthttp"
-	"math/rand"
+	"io"
+	"net"
+	neturl "net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-var baseUserAgents = []string{
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0",
-	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.1 Safari/605.1.15",
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36 Edg/91.0.864.59",
-	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
-}
-
-var acceptLanguages = []string{
-	"en-US,en;q=0.9", "en-GB,en;q=0.8", "es-ES,es;q=0.9",
-	"fr-FR,fr;q=0.9", "de-DE,de;q=0.8", "it-IT,it;q=0.9",
-}
-
 type Client struct {
-	config config.Config
-	client *fasthttp.Client
+	config      config.Config
+	client      *fasthttp.Client
+	bodyScanner *result.BodyScanner
+	timeouts    *hosts.Timeouts
+	admission   *control.Admission
+	overrides   *request.Overrides
+	noRange     bool
+	// certificates holds the certificate of every host seen in a TLS handshake, keyed by hostname
+	certificates sync.Map
 }
 
 func NewClient(cfg config.Config) *Client {
-	return &Client{
+	c := &Client{
 		config: cfg,
 		client: &fasthttp.Client{
 			ReadTimeout:                   cfg.Timeout,
 			WriteTimeout:                  cfg.Timeout,
 			DisablePathNormalizing:        true,
 			DisableHeaderNamesNormalizing: true, // Prevent automatic header modifications
+			StreamResponseBody:            true, // Read at most MaxContentRead bytes of large bodies
 			TLSConfig: &tls.Config{
 				InsecureSkipVerify: true,
+				ServerName:         cfg.SNI,
 			},
 		},
 	}
+	if cfg.Resolver != nil {
+		c.client.Dial = func(addr string) (net.Conn, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+			defer cancel()
+			return cfg.Resolver.DialContext(ctx, "tcp", addr)
+		}
+	}
+	if cfg.MinTransferRate > 0 {
+		// fasthttp only streams bodies larger than MaxResponseBodySize, smaller ones would be read
+		// without the transfer rate check
+		c.client.MaxResponseBodySize = 1
+	}
+	if cfg.TLSInfo {
+		// fasthttp does not expose the connection state of a response, the handshakes of every
+		// host are observed instead. The server name of the state is empty for IP addresses.
+		c.client.ConfigureClient = func(hc *fasthttp.HostClient) error {
+			if hc.IsTLS {
+				hostname, _, err := net.SplitHostPort(hc.Addr)
+				if err != nil {
+					hostname = hc.Addr
+				}
+				hc.TLSConfig = hc.TLSConfig.Clone()
+				hc.TLSConfig.VerifyConnection = func(state tls.ConnectionState) error {
+					if len(state.PeerCertificates) > 0 {
+						c.certificates.Store(hostname, result.NewCertificate(state.PeerCertificates[0]))
+					}
+					return nil
+				}
+			}
+			return nil
+		}
+	}
+	return c
+}
+
+// certificate returns the certificate recorded for the host of rawURL, nil if there is none
+func (c *Client) certificate(rawURL string) *result.Certificate {
+	if !c.config.TLSInfo || !strings.HasPrefix(rawURL, "https://") {
+		return nil
+	}
+	parsed, err := neturl.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	if cert, ok := c.certificates.Load(parsed.Hostname()); ok {
+		return cert.(*result.Certificate)
+	}
+	return nil
+}
+
+// SetBodyScanner enables stopping the body download once the markers are decided
+func (c *Client) SetBodyScanner(bodyScanner *result.BodyScanner) {
+	c.bodyScanner = bodyScanner
+}
+
+// SetTimeouts enables per-host timeouts adapted to the response times of each host
+func (c *Client) SetTimeouts(timeouts *hosts.Timeouts) {
+	c.timeouts = timeouts
+}
+
+// SetAdmission reduces the body bytes read while the memory is under pressure
+func (c *Client) SetAdmission(admission *control.Admission) {
+	c.admission = admission
+}
+
+// DisableRange requests the whole body instead of sending a Range header, e.g. for verification
+// requests of matches found in a partial read
+func (c *Client) DisableRange() {
+	c.noRange = true
+}
+
+// SetOverrides replaces the GET of the URLs matching overrides
+func (c *Client) SetOverrides(overrides *request.Overrides) {
+	c.overrides = overrides
 }
 
 func (c *Client) MakeRequest(url string) result.Result {
@@ -52,39 +131,77 @@ func (c *Client) MakeRequest(url string) result.Result {
 	req.SetRequestURI(url)
 	req.URI().DisablePathNormalizing = true
 	req.Header.DisableNormalizing()
-	req.Header.SetMethod(fasthttp.MethodGet)
+	spec, overridden := c.overrides.Lookup(url)
+	if overridden {
+		req.Header.SetMethod(spec.Method)
+		req.SetBody(spec.Body)
+	} else {
+		req.Header.SetMethod(fasthttp.MethodGet)
+	}
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.SetProtocol("HTTP/1.1")
-	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", c.config.MaxContentRead-1))
+	if !overridden && !c.noRange {
+		requestLimit := c.admission.ContentReadLimit(c.config.RequestReadLimit())
+		req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", requestLimit-1))
+	}
 
-	randomizeRequest(req)
+	validators, conditional := c.config.Conditions.Lookup(url)
+	conditional = conditional && !overridden && !c.noRange
+	if conditional {
+		if validators.ETag != "" {
+			req.Header.Set("If-None-Match", validators.ETag)
+		}
+		if validators.LastModified != "" {
+			req.Header.Set("If-Modified-Since", validators.LastModified)
+		}
+	}
+
+	if c.timeouts != nil {
+		req.SetTimeout(c.timeouts.Timeout(url))
+	}
+
+	setPersonaHeaders(req, c.config.Personas)
 	for key, value := range c.config.ExtraHeaders {
 		req.Header.Set(key, value)
 	}
+	for key, value := range spec.Headers {
+		req.Header.Set(key, value)
+	}
 
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseResponse(resp)
 
-	client := &fasthttp.Client{
-		ReadTimeout:                   c.config.Timeout,
-		WriteTimeout:                  c.config.Timeout,
-		DisableHeaderNamesNormalizing: true,
-		DisablePathNormalizing:        true,
-		TLSConfig: &tls.Config{
-			InsecureSkipVerify: true,
-		},
+	// The shared client keeps connections alive across requests; request and response
+	// (including the body buffer) are recycled by fasthttp
+	start := time.Now()
+	err := c.client.DoRedirects(req, resp, 0)
+	if c.timeouts != nil {
+		c.timeouts.Observe(url, time.Since(start), err != nil)
 	}
-
-	err := client.DoRedirects(req, resp, 0)
 	if err == fasthttp.ErrMissingLocation {
-		return result.Result{URL: url, Error: fmt.Errorf("error fetching: %w", err)}
+		return result.Result{URL: url, Error: fmt.Errorf("error fetching: %w", err), Duration: time.Since(start)}
 	}
 
 	if err != nil {
-		return result.Result{URL: url, Error: fmt.Errorf("error fetching: %w", err)}
+		return result.Result{URL: url, Error: fmt.Errorf("error fetching: %w", err), Duration: time.Since(start)}
 	}
-
-	body := resp.Body()
+	readLimit := c.admission.ContentReadLimit(c.config.ReadLimit(string(resp.Header.Peek("Content-Type"))))
+	var content string
+	if stream := resp.BodyStream(); stream != nil {
+		buffer := bufpool.Get()
+		defer bufpool.Put(buffer)
+		if err := result.ReadBody(buffer, io.LimitReader(result.LimitRate(stream, c.config.MinTransferRate), readLimit), c.bodyScanner.NewSession(resp.Header.ContentLength() >= 0 || len(resp.Header.Peek("Content-Range")) > 0)); err != nil {
+			return result.Result{URL: url, Error: fmt.Errorf("error reading body: %w", err), Duration: time.Since(start)}
+		}
+		content = buffer.String()
+	} else {
+		body := resp.Body()
+		if int64(len(body)) > readLimit {
+			body = body[:readLimit]
+		}
+		content = string(body)
+	}
+	duration := time.Since(start)
 
 	var totalSize int64
 
@@ -94,63 +211,80 @@ func (c *Client) MakeRequest(url string) result.Result {
 		if len(parts) == 2 {
 			totalSize, _ = strconv.ParseInt(string(parts[1]), 10, 64)
 		}
+	} else if contentLength := int64(resp.Header.ContentLength()); contentLength > int64(len(content)) {
+		totalSize = contentLength
 	} else {
-		totalSize = int64(len(body))
+		totalSize = int64(len(content))
 	}
 
-	if int64(len(body)) > c.config.MaxContentRead {
-		body = body[:c.config.MaxContentRead]
+	res := result.Result{
+		URL:          url,
+		Content:      content,
+		TransferSize: transferSize(&resp.Header, len(content)),
+		StatusCode:   resp.StatusCode(),
+		FileSize:     totalSize,
+		ContentType:  string(resp.Header.Peek("Content-Type")),
+		Duration:     duration,
+		Server:       result.Fingerprint(string(resp.Header.Peek("Server")), poweredBy(&resp.Header)),
+		Certificate:  c.certificate(url),
 	}
-
-	return result.Result{
-		URL:         url,
-		Content:     string(body),
-		StatusCode:  resp.StatusCode(),
-		FileSize:    totalSize,
-		ContentType: string(resp.Header.Peek("Content-Type")),
+	res.ETag, res.LastModified = headerValue(&resp.Header, "ETag"), headerValue(&resp.Header, "Last-Modified")
+	if conditional && resp.StatusCode() == fasthttp.StatusNotModified {
+		// The validators stay known for the next scan if the 304 leaves them out
+		res.Unchanged = true
+		if res.ETag == "" {
+			res.ETag = validators.ETag
+		}
+		if res.LastModified == "" {
+			res.LastModified = validators.LastModified
+		}
 	}
+	if c.config.DedupHeader != "" {
+		res.DedupHeader = headerValue(&resp.Header, c.config.DedupHeader)
+	}
+	return res
 }
 
-func randomizeRequest(req *fasthttp.Request) {
-	req.Header.Set("User-Agent", getRandomUserAgent())
-	req.Header.Set("Accept-Language", getRandomAcceptLanguage())
+// headerValue returns the first value of the header name, compared case-insensitively since the
+// header names are not normalized
+func headerValue(header *fasthttp.ResponseHeader, name string) string {
+	var value string
+	header.VisitAll(func(key, v []byte) {
+		if value == "" && strings.EqualFold(string(key), name) {
+			value = string(v)
+		}
+	})
+	return value
+}
 
-	referer := getReferer(req.URI().String())
-	req.Header.Set("Referer", referer)
-	req.Header.Set("Origin", referer)
-	req.Header.Set("Accept", "*/*")
+// transferSize returns the bytes received for a response with header and bodyRead bytes of its body
+func transferSize(header *fasthttp.ResponseHeader, bodyRead int) int64 {
+	size := len("HTTP/1.1 200 \r\n") + len(header.StatusMessage())
+	header.VisitAll(func(key, value []byte) {
+		size += len(key) + len(value) + 4
+	})
+	return int64(size + 2 + bodyRead)
+}
 
-	if rand.Float32() < 0.5 {
-		req.Header.Set("DNT", "1")
-	}
-	if rand.Float32() < 0.3 {
-		req.Header.Set("Upgrade-Insecure-Requests", "1")
+// poweredBy joins all X-Powered-By headers, frameworks often add their own next to the server's
+func poweredBy(header *fasthttp.ResponseHeader) string {
+	values := header.PeekAll("X-Powered-By")
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = string(value)
 	}
+	return strings.Join(parts, ",")
 }
 
-func getRandomUserAgent() string {
-	baseUA := baseUserAgents[rand.Intn(len(baseUserAgents))]
-	parts := strings.Split(baseUA, " ")
-
-	for i, part := range parts {
-		if strings.Contains(part, "/") {
-			versionParts := strings.Split(part, "/")
-			if len(versionParts) == 2 {
-				version := strings.Split(versionParts[1], ".")
-				if len(version) > 2 {
-					version[2] = fmt.Sprintf("%d", rand.Intn(100))
-					versionParts[1] = strings.Join(version, ".")
-				}
-			}
-			parts[i] = strings.Join(versionParts, "/")
-		}
+// setPersonaHeaders sends the headers of a random persona of personas
+func setPersonaHeaders(req *fasthttp.Request, personas []request.Persona) {
+	for _, header := range request.RandomPersona(personas).Headers() {
+		req.Header.Set(header[0], header[1])
 	}
 
-	return strings.Join(parts, " ")
-}
-
-func getRandomAcceptLanguage() string {
-	return acceptLanguages[rand.Intn(len(acceptLanguages))]
+	referer := getReferer(req.URI().String())
+	req.Header.Set("Referer", referer)
+	req.Header.Set("Origin", referer)
 }
 
 func getReferer(url string) string {
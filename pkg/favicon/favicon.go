@@ -0,0 +1,127 @@
+// Package favicon fingerprints hosts by the Shodan-style mmh3 hash of their /favicon.ico.
+package favicon
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math/bits"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+type Client interface {
+	MakeRequest(url string) result.Result
+}
+
+type hostFavicon struct {
+	once sync.Once
+	hash string
+}
+
+// Fingerprinter requests the favicon of every host once and caches its hash
+type Fingerprinter struct {
+	client Client
+
+	mu    sync.Mutex
+	hosts map[string]*hostFavicon
+}
+
+func NewFingerprinter(client Client) *Fingerprinter {
+	return &Fingerprinter{
+		client: client,
+		hosts:  make(map[string]*hostFavicon),
+	}
+}
+
+// Hash returns the favicon hash of the host of rawURL, empty if the host has no favicon
+func (f *Fingerprinter) Hash(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	base := parsed.Scheme + "://" + parsed.Host
+
+	hf := f.getHost(base)
+	hf.once.Do(func() {
+		res := f.client.MakeRequest(base + "/favicon.ico")
+		if res.Error == nil && res.StatusCode == 200 && res.Content != "" {
+			hf.hash = strconv.Itoa(int(Hash([]byte(res.Content))))
+		}
+	})
+	return hf.hash
+}
+
+func (f *Fingerprinter) getHost(base string) *hostFavicon {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	hf, exists := f.hosts[base]
+	if !exists {
+		hf = &hostFavicon{}
+		f.hosts[base] = hf
+	}
+	return hf
+}
+
+// Hash returns the hash Shodan uses for http.favicon.hash: the signed 32-bit mmh3 of the base64
+// encoding with a line break after every 76 characters and at the end
+func Hash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	wrapped := make([]byte, 0, len(encoded)+len(encoded)/76+1)
+	for len(encoded) > 76 {
+		wrapped = append(wrapped, encoded[:76]...)
+		wrapped = append(wrapped, '\n')
+		encoded = encoded[76:]
+	}
+	wrapped = append(wrapped, encoded...)
+	wrapped = append(wrapped, '\n')
+	return int32(murmur3(wrapped))
+}
+
+// murmur3 is MurmurHash3 x86_32 with seed 0
+func murmur3(data []byte) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	var h uint32
+	blocks := len(data) / 4
+	for i := 0; i < blocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	tail := data[blocks*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
@@ -0,0 +1,67 @@
+package filetype
+
+import (
+	"strings"
+)
+
+type signature struct {
+	name   string
+	offset int
+	magic  string
+}
+
+var signatures = []signature{
+	{name: "zip", magic: "PK\x03\x04"},
+	{name: "zip", magic: "PK\x05\x06"},
+	{name: "gzip", magic: "\x1f\x8b"},
+	{name: "bzip2", magic: "BZh"},
+	{name: "xz", magic: "\xfd7zXZ\x00"},
+	{name: "7z", magic: "7z\xbc\xaf\x27\x1c"},
+	{name: "rar", magic: "Rar!\x1a\x07"},
+	{name: "tar", offset: 257, magic: "ustar"},
+	{name: "sqlite", magic: "SQLite format 3\x00"},
+	{name: "pe", magic: "MZ"},
+	{name: "elf", magic: "\x7fELF"},
+	{name: "pdf", magic: "%PDF-"},
+	{name: "pgdump", magic: "PGDMP"},
+}
+
+// Text dumps have no magic bytes, so we look for the usual dump headers/statements at the beginning
+var sqlPrefixes = []string{
+	"-- mysql dump",
+	"-- mariadb dump",
+	"-- postgresql database dump",
+	"-- phpmyadmin sql dump",
+	"/*!40101 set",
+	"create table",
+	"create database",
+	"insert into",
+	"drop table",
+	"set names",
+	"begin transaction",
+	"pragma foreign_keys",
+}
+
+// Detect returns the detected file type of content (e.g. zip, gzip, sqlite, sql) or an empty string.
+func Detect(content string) string {
+	for _, sig := range signatures {
+		end := sig.offset + len(sig.magic)
+		if len(content) >= end && content[sig.offset:end] == sig.magic {
+			return sig.name
+		}
+	}
+
+	head := content
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	head = strings.ToLower(strings.TrimSpace(head))
+
+	for _, prefix := range sqlPrefixes {
+		if strings.HasPrefix(head, prefix) {
+			return "sql"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,167 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"github.com/fatih/color"
+)
+
+// Executor runs a user command for every finding. The command template is split into arguments
+// once and started without a shell, the placeholders are replaced within the arguments, so URLs
+// cannot break out of their argument on any platform.
+type Executor struct {
+	args    []string
+	timeout time.Duration
+	verbose bool
+	slots   chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewExecutor fails if template has no command or an unterminated quote. Commands running longer
+// than timeout are killed.
+func NewExecutor(template string, parallel int, timeout time.Duration, verbose bool) (*Executor, error) {
+	args, err := splitArgs(template)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	return &Executor{
+		args:    args,
+		timeout: timeout,
+		verbose: verbose,
+		slots:   make(chan struct{}, parallel),
+	}, nil
+}
+
+// Run starts the command for finding in the background, it is killed once ctx is cancelled. While
+// the maximum number of commands is running Run waits for a free slot up to the timeout and skips
+// the finding after it, so hanging commands do not stall the result processing.
+func (e *Executor) Run(ctx context.Context, finding result.Finding) {
+	if e == nil {
+		return
+	}
+
+	args := expand(e.args, finding)
+
+	wait := time.NewTimer(e.timeout)
+	defer wait.Stop()
+	select {
+	case e.slots <- struct{}{}:
+	case <-wait.C:
+		color.Yellow("\n[!] Skipped the command for %s, all %d command slots stayed busy for %s", finding.URL, cap(e.slots), e.timeout)
+		return
+	case <-ctx.Done():
+		return
+	}
+	e.wg.Add(1)
+	go func() {
+		defer func() {
+			<-e.slots
+			e.wg.Done()
+		}()
+
+		ctx, cancel := context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+		if err != nil {
+			color.Yellow("\n[!] Command for %s failed: %v", finding.URL, err)
+		}
+		if e.verbose && len(out) > 0 {
+			log.Printf("Output of '%s':\n%s", strings.Join(args, " "), out)
+		}
+	}()
+}
+
+// Wait blocks until all started commands finished
+func (e *Executor) Wait() {
+	if e == nil {
+		return
+	}
+	e.wg.Wait()
+}
+
+// expand replaces {{url}}, {{host}}, {{path}} and {{detection}} in every argument
+func expand(args []string, finding result.Finding) []string {
+	host, path := finding.URL, ""
+	if parsed, err := url.Parse(finding.URL); err == nil {
+		host, path = parsed.Host, parsed.Path
+	}
+
+	replacer := strings.NewReplacer(
+		"{{url}}", finding.URL,
+		"{{host}}", host,
+		"{{path}}", path,
+		"{{detection}}", finding.Detection,
+	)
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = replacer.Replace(arg)
+	}
+	return expanded
+}
+
+// splitArgs splits command at unquoted whitespace like a POSIX shell. Single quotes keep their
+// content literally, within double quotes and outside of quotes a backslash escapes the next
+// character.
+func splitArgs(command string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, c := range command {
+		switch {
+		case escaped:
+			arg.WriteRune(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(c)
+			}
+		case c == '\\':
+			escaped = true
+			inArg = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				arg.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
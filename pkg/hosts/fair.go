@@ -0,0 +1,47 @@
+package hosts
+
+// FairQueue holds the pending URLs of several hosts and hands them out round-robin, so a host
+// with thousands of URLs does not occupy all workers while the other hosts wait.
+type FairQueue struct {
+	queues [][]string
+	next   int
+}
+
+func NewFairQueue() *FairQueue {
+	return &FairQueue{}
+}
+
+// Add queues the URLs of one host
+func (q *FairQueue) Add(urls []string) {
+	if len(urls) > 0 {
+		q.queues = append(q.queues, urls)
+	}
+}
+
+// Hosts returns the number of hosts with pending URLs
+func (q *FairQueue) Hosts() int {
+	return len(q.queues)
+}
+
+// Next returns the next URL of the next host in turn, or false if no URLs are pending
+func (q *FairQueue) Next() (string, bool) {
+	if len(q.queues) == 0 {
+		return "", false
+	}
+
+	if q.next >= len(q.queues) {
+		q.next = 0
+	}
+
+	url := q.queues[q.next][0]
+	q.queues[q.next] = q.queues[q.next][1:]
+
+	if len(q.queues[q.next]) == 0 {
+		// The host is done, the following host takes over its turn
+		q.queues = append(q.queues[:q.next], q.queues[q.next+1:]...)
+	} else {
+		q.next++
+	}
+
+	return url, true
+}
@@ -0,0 +1,58 @@
+package hosts
+
+import (
+	"net/url"
+	"sync"
+)
+
+// MatchTracker counts confirmed matches per host so that hosts can be muted (and optionally skipped)
+// once they yielded enough findings.
+type MatchTracker struct {
+	mu           sync.RWMutex
+	matches      map[string]int
+	maxMatches   int
+	stopRequests bool
+}
+
+func NewMatchTracker(maxMatches int, stopRequests bool) *MatchTracker {
+	return &MatchTracker{
+		matches:      make(map[string]int),
+		maxMatches:   maxMatches,
+		stopRequests: stopRequests,
+	}
+}
+
+func (t *MatchTracker) RecordMatch(rawURL string) {
+	host := Host(rawURL)
+
+	t.mu.Lock()
+	t.matches[host]++
+	t.mu.Unlock()
+}
+
+// Muted reports whether the host of rawURL reached the maximum number of matches.
+func (t *MatchTracker) Muted(rawURL string) bool {
+	if t.maxMatches <= 0 {
+		return false
+	}
+
+	host := Host(rawURL)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.matches[host] >= t.maxMatches
+}
+
+// Stopped reports whether the host of rawURL should not be requested anymore.
+func (t *MatchTracker) Stopped(rawURL string) bool {
+	return t.stopRequests && t.Muted(rawURL)
+}
+
+// Host returns the host (including the port) of rawURL or rawURL itself if it cannot be parsed.
+func Host(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsedURL.Host
+}
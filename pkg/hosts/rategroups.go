@@ -0,0 +1,94 @@
+package hosts
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+type rateGroup struct {
+	// host is matched exactly, suffix (".example.com" of "*.example.com") matches the subdomains
+	host    string
+	suffix  string
+	limiter *rate.Limiter
+}
+
+// RateGroups limits the requests per second of host patterns, e.g. "*.fragile-partner.com 1". All
+// hosts matching a pattern share its limit. A nil *RateGroups limits nothing.
+type RateGroups struct {
+	groups []rateGroup
+}
+
+// NewRateGroups reads the groups of filename, one "<pattern> <requests per second>" per line
+func NewRateGroups(filename string) (*RateGroups, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	g := &RateGroups{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected '<pattern> <requests per second>'", filename, lineNumber)
+		}
+		rps, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || rps <= 0 {
+			return nil, fmt.Errorf("%s:%d: invalid requests per second '%s'", filename, lineNumber, fields[1])
+		}
+
+		burst := int(rps)
+		if burst < 1 {
+			burst = 1
+		}
+		group := rateGroup{limiter: rate.NewLimiter(rate.Limit(rps), burst)}
+		pattern := strings.ToLower(fields[0])
+		if strings.HasPrefix(pattern, "*.") {
+			group.suffix = strings.TrimPrefix(pattern, "*")
+		} else {
+			group.host = pattern
+		}
+		g.groups = append(g.groups, group)
+	}
+	return g, scanner.Err()
+}
+
+// Len returns the number of groups
+func (g *RateGroups) Len() int {
+	if g == nil {
+		return 0
+	}
+	return len(g.groups)
+}
+
+// Wait blocks until the group of the host of rawURL allows a request, the first matching pattern
+// applies. URLs of hosts without a group return immediately.
+func (g *RateGroups) Wait(ctx context.Context, rawURL string) error {
+	if g == nil {
+		return nil
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, group := range g.groups {
+		if host == group.host || (group.suffix != "" && (strings.HasSuffix(host, group.suffix) || host == group.suffix[1:])) {
+			return group.limiter.Wait(ctx)
+		}
+	}
+	return nil
+}
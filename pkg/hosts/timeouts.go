@@ -0,0 +1,109 @@
+package hosts
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	timeoutSamples     = 5
+	timeoutFactor      = 4
+	minAdaptiveTimeout = time.Second
+	deadHostFailures   = 3
+)
+
+// Timeouts adapts the request timeout per host to its response times. Until a host answered a few
+// requests the fallback timeout is used, afterwards a multiple of its slowest recent response time,
+// between minAdaptiveTimeout and max. Hosts which never answered get the minimum after a few failures.
+type Timeouts struct {
+	fallback time.Duration
+	max      time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostTimes
+}
+
+type hostTimes struct {
+	samples   []time.Duration
+	next      int
+	failures  int
+	succeeded bool
+}
+
+func NewTimeouts(fallback, max time.Duration) *Timeouts {
+	if max < fallback {
+		max = fallback
+	}
+	return &Timeouts{
+		fallback: fallback,
+		max:      max,
+		hosts:    make(map[string]*hostTimes),
+	}
+}
+
+// Timeout returns the timeout for a request to rawURL
+func (t *Timeouts) Timeout(rawURL string) time.Duration {
+	host := Host(rawURL)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	times, ok := t.hosts[host]
+	if !ok {
+		return t.fallback
+	}
+
+	if !times.succeeded {
+		if times.failures >= deadHostFailures {
+			return minAdaptiveTimeout
+		}
+		return t.fallback
+	}
+
+	if len(times.samples) < timeoutSamples {
+		return t.fallback
+	}
+
+	var slowest time.Duration
+	for _, sample := range times.samples {
+		if sample > slowest {
+			slowest = sample
+		}
+	}
+
+	timeout := slowest * timeoutFactor
+	if timeout < minAdaptiveTimeout {
+		return minAdaptiveTimeout
+	}
+	if timeout > t.max {
+		return t.max
+	}
+	return timeout
+}
+
+// Observe records the response time of a request to rawURL or that it failed without a response
+func (t *Timeouts) Observe(rawURL string, duration time.Duration, failed bool) {
+	host := Host(rawURL)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	times, ok := t.hosts[host]
+	if !ok {
+		times = &hostTimes{}
+		t.hosts[host] = times
+	}
+
+	if failed {
+		times.failures++
+		return
+	}
+
+	times.succeeded = true
+	if len(times.samples) < timeoutSamples {
+		times.samples = append(times.samples, duration)
+		return
+	}
+	times.samples[times.next] = duration
+	times.next = (times.next + 1) % timeoutSamples
+}
@@ -0,0 +1,95 @@
+package hosts
+
+import (
+	"sync"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+type wildcardCounts struct {
+	responses int
+	hits      int
+	flagged   bool
+}
+
+// WildcardDetector flags hosts which answer an implausibly high fraction of the requests with a 200
+// of at least the minimum content size, e.g. catch-all routes serving the same page for every path.
+// A nil *WildcardDetector flags nothing.
+type WildcardDetector struct {
+	mu          sync.Mutex
+	hosts       map[string]*wildcardCounts
+	ratio       float64
+	minRequests int
+	minSize     int64
+}
+
+// NewWildcardDetector flags a host once at least ratio of its first minRequests or more responses
+// were a 200 of at least minSize bytes. It returns nil if ratio is zero.
+func NewWildcardDetector(ratio float64, minRequests int, minSize int64) *WildcardDetector {
+	if ratio <= 0 {
+		return nil
+	}
+	return &WildcardDetector{
+		hosts:       make(map[string]*wildcardCounts),
+		ratio:       ratio,
+		minRequests: minRequests,
+		minSize:     minSize,
+	}
+}
+
+// Observe counts res and reports whether its host was flagged by it. A host is flagged only once.
+func (d *WildcardDetector) Observe(res result.Result) bool {
+	if d == nil || res.Error != nil || res.Blocked {
+		return false
+	}
+	host := Host(res.URL)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	counts, ok := d.hosts[host]
+	if !ok {
+		counts = &wildcardCounts{}
+		d.hosts[host] = counts
+	}
+	if counts.flagged {
+		return false
+	}
+
+	counts.responses++
+	if res.StatusCode == 200 && !res.SoftNotFound && res.FileSize >= d.minSize {
+		counts.hits++
+	}
+	if counts.responses >= d.minRequests && float64(counts.hits) >= d.ratio*float64(counts.responses) {
+		counts.flagged = true
+		return true
+	}
+	return false
+}
+
+// Flagged reports whether the host of rawURL is a wildcard responder
+func (d *WildcardDetector) Flagged(rawURL string) bool {
+	if d == nil {
+		return false
+	}
+	host := Host(rawURL)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	counts, ok := d.hosts[host]
+	return ok && counts.flagged
+}
+
+// Counts returns the number of responses and hits of the host of rawURL
+func (d *WildcardDetector) Counts(rawURL string) (responses, hits int) {
+	if d == nil {
+		return 0, 0
+	}
+	host := Host(rawURL)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if counts, ok := d.hosts[host]; ok {
+		return counts.responses, counts.hits
+	}
+	return 0, 0
+}
@@ -1,36 +1,32 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
-	"math/rand"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/bufpool"
 	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/control"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/hosts"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/request"
 	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
 )
 
-var baseUserAgents = []string{
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0",
-	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.1 Safari/605.1.15",
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36 Edg/91.0.864.59",
-	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
-}
-
-var acceptLanguages = []string{
-	"en-US,en;q=0.9", "en-GB,en;q=0.8", "es-ES,es;q=0.9",
-	"fr-FR,fr;q=0.9", "de-DE,de;q=0.8", "it-IT,it;q=0.9",
-}
-
 type Client struct {
-	httpClient *http.Client
-	config     config.Config
+	httpClient  *http.Client
+	config      config.Config
+	bodyScanner *result.BodyScanner
+	timeouts    *hosts.Timeouts
+	admission   *control.Admission
+	overrides   *request.Overrides
+	noRange     bool
 }
 
 func NewClient(cfg config.Config) *Client {
@@ -38,16 +34,25 @@ func NewClient(cfg config.Config) *Client {
 		MaxIdleConns:        100,
 		MaxIdleConnsPerHost: 100,
 		IdleConnTimeout:     90 * time.Second,
-		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true, ServerName: cfg.SNI},
 	}
 
-	if cfg.ProxyURL != nil {
-		transport.Proxy = http.ProxyURL(cfg.ProxyURL)
+	if cfg.Proxies != nil {
+		transport.Proxy = cfg.Proxies.Proxy
+	}
+	if cfg.Resolver != nil {
+		transport.DialContext = cfg.Resolver.DialContext
+	}
+
+	// The per-request timeout is enforced by the request context, this is just a safety net
+	maxTimeout := cfg.Timeout
+	if cfg.AdaptiveTimeout && cfg.AdaptiveTimeoutMax > maxTimeout {
+		maxTimeout = cfg.AdaptiveTimeoutMax
 	}
 
 	client := &http.Client{
 		Transport: transport,
-		Timeout:   cfg.Timeout + 3*time.Second,
+		Timeout:   maxTimeout + 3*time.Second,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
@@ -59,31 +64,93 @@ func NewClient(cfg config.Config) *Client {
 	}
 }
 
+// SetBodyScanner enables stopping the body download once the markers are decided
+func (c *Client) SetBodyScanner(bodyScanner *result.BodyScanner) {
+	c.bodyScanner = bodyScanner
+}
+
+// SetTimeouts enables per-host timeouts adapted to the response times of each host
+func (c *Client) SetTimeouts(timeouts *hosts.Timeouts) {
+	c.timeouts = timeouts
+}
+
+// SetAdmission reduces the body bytes read while the memory is under pressure
+func (c *Client) SetAdmission(admission *control.Admission) {
+	c.admission = admission
+}
+
+// DisableRange requests the whole body instead of sending a Range header, e.g. for verification
+// requests of matches found in a partial read
+func (c *Client) DisableRange() {
+	c.noRange = true
+}
+
+// SetOverrides replaces the GET of the URLs matching overrides
+func (c *Client) SetOverrides(overrides *request.Overrides) {
+	c.overrides = overrides
+}
+
 func (c *Client) MakeRequest(url string) result.Result {
-	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	timeout := c.config.Timeout
+	if c.timeouts != nil {
+		timeout = c.timeouts.Timeout(url)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	spec, overridden := c.overrides.Lookup(url)
+	method := "GET"
+	var body io.Reader
+	if overridden {
+		method = spec.Method
+		body = bytes.NewReader(spec.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return result.Result{URL: url, Error: fmt.Errorf("error creating request: %w", err)}
 	}
 
-	randomizeRequest(req)
+	setPersonaHeaders(req, c.config.Personas)
 
 	for key, value := range c.config.ExtraHeaders {
 		req.Header.Set(key, value)
 	}
+	for key, value := range spec.Headers {
+		req.Header.Set(key, value)
+	}
 
-	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", c.config.MaxContentRead-1))
+	if !overridden && !c.noRange {
+		requestLimit := c.admission.ContentReadLimit(c.config.RequestReadLimit())
+		req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", requestLimit-1))
+	}
+	validators, conditional := c.config.Conditions.Lookup(url)
+	conditional = conditional && !overridden && !c.noRange
+	if conditional {
+		if validators.ETag != "" {
+			req.Header.Set("If-None-Match", validators.ETag)
+		}
+		if validators.LastModified != "" {
+			req.Header.Set("If-Modified-Since", validators.LastModified)
+		}
+	}
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
+	if c.timeouts != nil {
+		c.timeouts.Observe(url, time.Since(start), err != nil)
+	}
 	if err != nil {
-		return result.Result{URL: url, Error: fmt.Errorf("error fetching: %w", err)}
+		return result.Result{URL: url, Error: fmt.Errorf("error fetching: %w", err), Duration: time.Since(start)}
 	}
 	defer resp.Body.Close()
 
-	buffer, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return result.Result{URL: url, Error: fmt.Errorf("error reading body: %w", err)}
+	// The read buffer is recycled, only the final content is allocated per request.
+	// Servers ignoring the Range header must not make us read more than the read limit.
+	readLimit := c.admission.ContentReadLimit(c.config.ReadLimit(resp.Header.Get("Content-Type")))
+	buffer := bufpool.Get()
+	defer bufpool.Put(buffer)
+	if err := result.ReadBody(buffer, io.LimitReader(result.LimitRate(resp.Body, c.config.MinTransferRate), readLimit), c.bodyScanner.NewSession(resp.ContentLength >= 0 || resp.Header.Get("Content-Range") != "")); err != nil {
+		return result.Result{URL: url, Error: fmt.Errorf("error reading body: %w", err), Duration: time.Since(start)}
 	}
 
 	var totalSize int64
@@ -92,59 +159,62 @@ func (c *Client) MakeRequest(url string) result.Result {
 		if len(parts) == 2 {
 			totalSize, _ = strconv.ParseInt(parts[1], 10, 64)
 		}
+	} else if resp.ContentLength > int64(buffer.Len()) {
+		totalSize = resp.ContentLength
 	} else {
-		totalSize = int64(len(buffer))
+		totalSize = int64(buffer.Len())
 	}
 
-	return result.Result{
-		URL:         url,
-		Content:     string(buffer),
-		StatusCode:  resp.StatusCode,
-		FileSize:    totalSize,
-		ContentType: resp.Header.Get("Content-Type"),
+	res := result.Result{
+		URL:          url,
+		Content:      buffer.String(),
+		TransferSize: transferSize(resp, buffer.Len()),
+		StatusCode:   resp.StatusCode,
+		FileSize:     totalSize,
+		ContentType:  resp.Header.Get("Content-Type"),
+		Duration:     time.Since(start),
+		Server:       result.Fingerprint(resp.Header.Get("Server"), strings.Join(resp.Header.Values("X-Powered-By"), ",")),
 	}
-}
-
-func randomizeRequest(req *http.Request) {
-	req.Header.Set("User-Agent", getRandomUserAgent())
-	req.Header.Set("Accept-Language", getRandomAcceptLanguage())
-
-	referer := getReferer(req.URL.String())
-	req.Header.Set("Referer", referer)
-	req.Header.Set("Origin", referer)
-	req.Header.Set("Accept", "*/*")
-
-	if rand.Float32() < 0.5 {
-		req.Header.Set("DNT", "1")
+	res.ETag, res.LastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if conditional && resp.StatusCode == http.StatusNotModified {
+		// The validators stay known for the next scan if the 304 leaves them out
+		res.Unchanged = true
+		if res.ETag == "" {
+			res.ETag = validators.ETag
+		}
+		if res.LastModified == "" {
+			res.LastModified = validators.LastModified
+		}
+	}
+	if c.config.DedupHeader != "" {
+		res.DedupHeader = resp.Header.Get(c.config.DedupHeader)
 	}
-	if rand.Float32() < 0.3 {
-		req.Header.Set("Upgrade-Insecure-Requests", "1")
+	if c.config.TLSInfo && resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		res.Certificate = result.NewCertificate(resp.TLS.PeerCertificates[0])
 	}
+	return res
 }
 
-func getRandomUserAgent() string {
-	baseUA := baseUserAgents[rand.Intn(len(baseUserAgents))]
-	parts := strings.Split(baseUA, " ")
-
-	for i, part := range parts {
-		if strings.Contains(part, "/") {
-			versionParts := strings.Split(part, "/")
-			if len(versionParts) == 2 {
-				version := strings.Split(versionParts[1], ".")
-				if len(version) > 2 {
-					version[2] = fmt.Sprintf("%d", rand.Intn(100))
-					versionParts[1] = strings.Join(version, ".")
-				}
-			}
-			parts[i] = strings.Join(versionParts, "/")
+// transferSize returns the bytes received for resp with bodyRead bytes of its body
+func transferSize(resp *http.Response, bodyRead int) int64 {
+	size := len(resp.Proto) + len(resp.Status) + 3
+	for key, values := range resp.Header {
+		for _, value := range values {
+			size += len(key) + len(value) + 4
 		}
 	}
-
-	return strings.Join(parts, " ")
+	return int64(size + 2 + bodyRead)
 }
 
-func getRandomAcceptLanguage() string {
-	return acceptLanguages[rand.Intn(len(acceptLanguages))]
+// setPersonaHeaders sends the headers of a random persona of personas
+func setPersonaHeaders(req *http.Request, personas []request.Persona) {
+	for _, header := range request.RandomPersona(personas).Headers() {
+		req.Header.Set(header[0], header[1])
+	}
+
+	referer := getReferer(req.URL.String())
+	req.Header.Set("Referer", referer)
+	req.Header.Set("Origin", referer)
 }
 
 func getReferer(url string) string {
@@ -0,0 +1,184 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/utils"
+)
+
+// Characters which turn a Burp scope host expression into a real pattern we cannot enumerate
+var regexMetaRegex = regexp.MustCompile(`[*+?()\[\]{}|]`)
+
+type burpItem struct {
+	Host     string `xml:"host"`
+	Port     int    `xml:"port"`
+	Protocol string `xml:"protocol"`
+	Path     string `xml:"path"`
+}
+
+type burpScope struct {
+	Target struct {
+		Scope struct {
+			Include []struct {
+				Enabled  bool   `json:"enabled"`
+				Prefix   string `json:"prefix"`
+				Host     string `json:"host"`
+				Port     string `json:"port"`
+				Protocol string `json:"protocol"`
+			} `json:"include"`
+		} `json:"scope"`
+	} `json:"target"`
+}
+
+// ReadBurp reads a Burp Suite site map export (XML, "Save selected items") or a target scope export
+// (JSON, "Project options > Save") and returns the base URLs of its hosts, e.g. https://example.com:8443.
+// For site maps the directories of the observed requests are returned as well, scope exports have none.
+func ReadBurp(filename string) (targets, dirs []string, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	first, err := firstNonSpace(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read %s: %w", filename, err)
+	}
+
+	if first == '{' {
+		targets, err = readBurpScope(reader)
+	} else {
+		targets, dirs, err = readBurpSiteMap(reader)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse %s: %w", filename, err)
+	}
+
+	return utils.UniqueStrings(targets), utils.UniqueStrings(dirs), nil
+}
+
+func firstNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, reader.UnreadByte()
+		}
+	}
+}
+
+func readBurpSiteMap(r io.Reader) (targets, dirs []string, err error) {
+	// Items are decoded one by one, site maps with stored responses can be huge
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return targets, dirs, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "item" {
+			continue
+		}
+
+		var item burpItem
+		if err := decoder.DecodeElement(&item, &start); err != nil {
+			return nil, nil, err
+		}
+		if item.Host == "" {
+			continue
+		}
+
+		targets = append(targets, baseURL(item.Protocol, item.Host, fmt.Sprint(item.Port)))
+		dirs = append(dirs, pathDirs(item.Path)...)
+	}
+}
+
+func readBurpScope(r io.Reader) ([]string, error) {
+	var scope burpScope
+	if err := json.NewDecoder(r).Decode(&scope); err != nil {
+		return nil, err
+	}
+
+	var targets []string
+	for _, include := range scope.Target.Scope.Include {
+		if !include.Enabled {
+			continue
+		}
+
+		if include.Prefix != "" {
+			parsed, err := url.Parse(include.Prefix)
+			if err != nil || parsed.Host == "" {
+				continue
+			}
+			targets = append(targets, parsed.Scheme+"://"+parsed.Host)
+			continue
+		}
+
+		// Advanced scope rules are regular expressions, only literal hosts can be scanned
+		host := unescapeLiteral(include.Host)
+		if host == "" {
+			continue
+		}
+		targets = append(targets, baseURL(include.Protocol, host, unescapeLiteral(include.Port)))
+	}
+
+	return targets, nil
+}
+
+// unescapeLiteral turns an anchored, escaped literal like ^www\.example\.com$ into www.example.com
+// and returns an empty string for real patterns
+func unescapeLiteral(expression string) string {
+	expression = strings.TrimSuffix(strings.TrimPrefix(expression, "^"), "$")
+	literal := strings.ReplaceAll(expression, `\.`, ".")
+	if regexMetaRegex.MatchString(literal) || strings.Contains(literal, `\`) {
+		return ""
+	}
+	return literal
+}
+
+func baseURL(protocol, host, port string) string {
+	if protocol != "http" {
+		protocol = "https"
+	}
+	if port == "" || port == "0" || (protocol == "https" && port == "443") || (protocol == "http" && port == "80") {
+		return protocol + "://" + host
+	}
+	return protocol + "://" + host + ":" + port
+}
+
+// pathDirs returns all directories of a request path, e.g. app and app/admin for /app/admin/login.php
+func pathDirs(path string) []string {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if !strings.HasSuffix(path, "/") {
+		// The last part is the requested file
+		parts = parts[:len(parts)-1]
+	}
+
+	var dirs []string
+	for i := range parts {
+		if parts[i] == "" {
+			break
+		}
+		dirs = append(dirs, strings.Join(parts[:i+1], "/"))
+	}
+	return dirs
+}
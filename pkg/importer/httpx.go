@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const maxTitleWords = 5
+
+var titleWordRegex = regexp.MustCompile(`[a-z0-9]+`)
+
+// Words that appear in many titles and say nothing about the application
+var titleStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "page": true, "home": true, "welcome": true,
+	"login": true, "index": true, "default": true, "error": true, "not": true, "found": true,
+	"www": true, "http": true, "https": true,
+}
+
+type httpxLine struct {
+	URL    string `json:"url"`
+	Input  string `json:"input"`
+	Scheme string `json:"scheme"`
+	Port   string `json:"port"`
+	Title  string `json:"title"`
+	Failed bool   `json:"failed"`
+}
+
+// ReadHttpx reads httpx -json output and returns the base URLs of all probed services, e.g.
+// http://example.com:8080, with the scheme and port httpx found. words maps each target (without
+// the scheme) to words from its page title which are used for the path generation.
+func ReadHttpx(filename string) (targets []string, words map[string][]string, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	seen := make(map[string]bool)
+	words = make(map[string][]string)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry httpxLine
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, nil, fmt.Errorf("%s:%d: %w", filename, lineNumber, err)
+		}
+		if entry.Failed {
+			continue
+		}
+
+		target := httpxTarget(entry)
+		if target == "" || seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
+
+		if hostWords := titleWords(entry.Title); len(hostWords) > 0 {
+			words[strings.SplitN(target, "://", 2)[1]] = hostWords
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return targets, words, nil
+}
+
+func httpxTarget(entry httpxLine) string {
+	// The host field holds the resolved IP, the URL keeps the probed hostname. A directory probed
+	// with httpx -path is kept as base path, files are not.
+	if entry.URL != "" {
+		if parsed, err := url.Parse(entry.URL); err == nil && parsed.Hostname() != "" {
+			target := baseURL(parsed.Scheme, parsed.Hostname(), parsed.Port())
+			if strings.HasSuffix(parsed.Path, "/") {
+				target += strings.TrimRight(parsed.Path, "/")
+			}
+			return target
+		}
+	}
+
+	if entry.Input == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(entry.Input, "https://"), "http://")
+	host = strings.Split(strings.Split(host, "/")[0], ":")[0]
+	return baseURL(entry.Scheme, host, entry.Port)
+}
+
+// titleWords returns up to maxTitleWords distinctive lowercase words of a page title
+func titleWords(title string) []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, word := range titleWordRegex.FindAllString(strings.ToLower(title), -1) {
+		if len(word) < 3 || titleStopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+		if len(words) == maxTitleWords {
+			break
+		}
+	}
+	return words
+}
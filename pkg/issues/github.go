@@ -0,0 +1,55 @@
+package issues
+
+import (
+	"net/http"
+	"net/url"
+)
+
+const githubAPI = "https://api.github.com"
+
+type github struct {
+	client *http.Client
+	repo   string
+	token  string
+	labels []string
+}
+
+// NewGitHubReporter opens the issues in repo (owner/name). The finding ID in the issue body is
+// looked up with the search API before an issue is opened.
+func NewGitHubReporter(repo, token string, labels []string, verbose bool) *Reporter {
+	return newReporter(&github{
+		client: &http.Client{Timeout: requestTimeout},
+		repo:   repo,
+		token:  token,
+		labels: labels,
+	}, verbose)
+}
+
+func (g *github) name() string {
+	return "GitHub"
+}
+
+func (g *github) authorize(req *http.Request) {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+g.token)
+}
+
+func (g *github) exists(id string) (bool, error) {
+	query := url.Values{}
+	query.Set("q", "repo:"+g.repo+" is:issue in:body \""+id+"\"")
+
+	var response struct {
+		TotalCount int `json:"total_count"`
+	}
+	err := doJSON(g.client, http.MethodGet, githubAPI+"/search/issues?"+query.Encode(), nil, &response, g.authorize)
+	return response.TotalCount > 0, err
+}
+
+func (g *github) create(id, title, body string) error {
+	payload := struct {
+		Title  string   `json:"title"`
+		Body   string   `json:"body"`
+		Labels []string `json:"labels,omitempty"`
+	}{title, body, g.labels}
+	return doJSON(g.client, http.MethodPost, githubAPI+"/repos/"+g.repo+"/issues", payload, nil, g.authorize)
+}
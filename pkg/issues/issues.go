@@ -0,0 +1,170 @@
+// Package issues opens an issue per finding in GitHub or Jira. Every issue carries an ID derived
+// from the finding URL, so repeated scans don't open the same issue twice.
+package issues
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"github.com/fatih/color"
+)
+
+const (
+	requestTimeout = 30 * time.Second
+	// queueSize bounds the findings waiting for their issue, the scan blocks beyond that
+	queueSize = 100
+)
+
+// tracker is the API of an issue tracker
+type tracker interface {
+	name() string
+	// exists reports whether an issue with id was already opened
+	exists(id string) (bool, error)
+	create(id, title, body string) error
+}
+
+// Reporter opens the issues in the background, one after another
+type Reporter struct {
+	tracker tracker
+	verbose bool
+
+	queue chan result.Finding
+	wg    sync.WaitGroup
+
+	mu     sync.Mutex
+	opened map[string]bool
+}
+
+func newReporter(t tracker, verbose bool) *Reporter {
+	r := &Reporter{
+		tracker: t,
+		verbose: verbose,
+		queue:   make(chan result.Finding, queueSize),
+		opened:  make(map[string]bool),
+	}
+	r.wg.Add(1)
+	go r.work()
+	return r
+}
+
+// Report queues the issue of finding
+func (r *Reporter) Report(finding result.Finding) {
+	if r == nil {
+		return
+	}
+	r.queue <- finding
+}
+
+// Close waits until the queued issues were opened
+func (r *Reporter) Close() {
+	if r == nil {
+		return
+	}
+	close(r.queue)
+	r.wg.Wait()
+}
+
+func (r *Reporter) work() {
+	defer r.wg.Done()
+
+	for finding := range r.queue {
+		id := FindingID(finding.URL)
+
+		r.mu.Lock()
+		opened := r.opened[id]
+		r.opened[id] = true
+		r.mu.Unlock()
+		if opened {
+			continue
+		}
+
+		exists, err := r.tracker.exists(id)
+		if err != nil {
+			color.Yellow("\n[!] Could not search %s issues for %s: %v", r.tracker.name(), finding.URL, err)
+			continue
+		}
+		if exists {
+			if r.verbose {
+				log.Printf("%s issue for %s already exists\n", r.tracker.name(), finding.URL)
+			}
+			continue
+		}
+
+		if err := r.tracker.create(id, title(finding), body(id, finding)); err != nil {
+			color.Yellow("\n[!] Could not open %s issue for %s: %v", r.tracker.name(), finding.URL, err)
+		} else if r.verbose {
+			log.Printf("Opened %s issue for %s\n", r.tracker.name(), finding.URL)
+		}
+	}
+}
+
+// FindingID identifies the issue of a finding URL
+func FindingID(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return "dfs-" + hex.EncodeToString(sum[:8])
+}
+
+func title(finding result.Finding) string {
+	return "Exposed file: " + finding.URL
+}
+
+func body(id string, finding result.Finding) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dynamic-file-searcher found an exposed file.\n\n")
+	fmt.Fprintf(&b, "- URL: %s\n", finding.URL)
+	fmt.Fprintf(&b, "- Detection: %s\n", finding.Detection)
+	fmt.Fprintf(&b, "- Status: %d\n", finding.StatusCode)
+	fmt.Fprintf(&b, "- Size: %d bytes\n", finding.FileSize)
+	if finding.ContentType != "" {
+		fmt.Fprintf(&b, "- Content type: %s\n", finding.ContentType)
+	}
+	fmt.Fprintf(&b, "\nFinding ID: %s\n", id)
+	return b.String()
+}
+
+// doJSON sends payload (if any) to url and decodes the response into response (if any)
+func doJSON(client *http.Client, method, url string, payload, response interface{}, authorize func(*http.Request)) error {
+	var reader io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	authorize(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if response == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
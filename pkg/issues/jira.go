@@ -0,0 +1,69 @@
+package issues
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type jira struct {
+	client    *http.Client
+	baseURL   string
+	project   string
+	issueType string
+	user      string
+	token     string
+	labels    []string
+}
+
+// NewJiraReporter opens the issues in project of the Jira instance at baseURL. The finding ID is
+// added as a label, which is looked up with JQL before an issue is opened.
+func NewJiraReporter(baseURL, project, issueType, user, token string, labels []string, verbose bool) *Reporter {
+	return newReporter(&jira{
+		client:    &http.Client{Timeout: requestTimeout},
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		project:   project,
+		issueType: issueType,
+		user:      user,
+		token:     token,
+		labels:    labels,
+	}, verbose)
+}
+
+func (j *jira) name() string {
+	return "Jira"
+}
+
+func (j *jira) authorize(req *http.Request) {
+	req.SetBasicAuth(j.user, j.token)
+}
+
+func (j *jira) exists(id string) (bool, error) {
+	query := url.Values{}
+	query.Set("jql", fmt.Sprintf("project = \"%s\" AND labels = \"%s\"", j.project, id))
+	query.Set("maxResults", "0")
+
+	var response struct {
+		Total int `json:"total"`
+	}
+	err := doJSON(j.client, http.MethodGet, j.baseURL+"/rest/api/2/search?"+query.Encode(), nil, &response, j.authorize)
+	return response.Total > 0, err
+}
+
+func (j *jira) create(id, title, body string) error {
+	type named struct {
+		Key  string `json:"key,omitempty"`
+		Name string `json:"name,omitempty"`
+	}
+	payload := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     named{Key: j.project},
+			"issuetype":   named{Name: j.issueType},
+			"summary":     title,
+			"description": body,
+			"labels":      append([]string{id}, j.labels...),
+		},
+	}
+	return doJSON(j.client, http.MethodPost, j.baseURL+"/rest/api/2/issue", payload, nil, j.authorize)
+}
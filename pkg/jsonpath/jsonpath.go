@@ -0,0 +1,175 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Only the commonly used subset of JSONPath is supported:
+// $.key, $['key'], $.list[0], $.list[*], $.*, and recursive descent $..key
+
+type step struct {
+	key       string
+	index     int
+	wildcard  bool
+	isIndex   bool
+	recursive bool
+}
+
+type Path struct {
+	steps []step
+}
+
+// Compile parses a JSONPath expression such as $.data[*].secretKey
+func Compile(expression string) (*Path, error) {
+	expression = strings.TrimSpace(expression)
+	if !strings.HasPrefix(expression, "$") {
+		return nil, fmt.Errorf("jsonpath must start with $: %s", expression)
+	}
+
+	var steps []step
+	rest := expression[1:]
+
+	for rest != "" {
+		recursive := false
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			recursive = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "["):
+		default:
+			return nil, fmt.Errorf("unexpected character in jsonpath %s at '%s'", expression, rest)
+		}
+
+		if strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in jsonpath %s", expression)
+			}
+			s, err := parseBracket(rest[1:end])
+			if err != nil {
+				return nil, err
+			}
+			s.recursive = recursive
+			steps = append(steps, s)
+			rest = rest[end+1:]
+			continue
+		}
+
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		name := rest[:end]
+		if name == "" {
+			return nil, fmt.Errorf("empty key in jsonpath %s", expression)
+		}
+		steps = append(steps, step{key: name, wildcard: name == "*", recursive: recursive})
+		rest = rest[end:]
+	}
+
+	return &Path{steps: steps}, nil
+}
+
+func parseBracket(content string) (step, error) {
+	content = strings.TrimSpace(content)
+
+	if content == "*" {
+		return step{wildcard: true}, nil
+	}
+
+	if len(content) >= 2 && (content[0] == '\'' || content[0] == '"') && content[len(content)-1] == content[0] {
+		return step{key: content[1 : len(content)-1]}, nil
+	}
+
+	index, err := strconv.Atoi(content)
+	if err != nil {
+		return step{}, fmt.Errorf("invalid jsonpath index '%s'", content)
+	}
+	return step{index: index, isIndex: true}, nil
+}
+
+// Find evaluates the path against the JSON document in body and returns all matched values.
+// Bodies which are not valid JSON never match.
+func (p *Path) Find(body string) []interface{} {
+	var document interface{}
+	if err := json.Unmarshal([]byte(body), &document); err != nil {
+		return nil
+	}
+
+	current := []interface{}{document}
+	for _, s := range p.steps {
+		var next []interface{}
+		for _, value := range current {
+			if s.recursive {
+				for _, descendant := range descendants(value) {
+					next = append(next, s.apply(descendant)...)
+				}
+				continue
+			}
+			next = append(next, s.apply(value)...)
+		}
+		current = next
+		if len(current) == 0 {
+			break
+		}
+	}
+
+	var found []interface{}
+	for _, value := range current {
+		if value != nil && value != "" {
+			found = append(found, value)
+		}
+	}
+	return found
+}
+
+func (s step) apply(value interface{}) []interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			values := make([]interface{}, 0, len(typed))
+			for _, v := range typed {
+				values = append(values, v)
+			}
+			return values
+		}
+		if v, exists := typed[s.key]; exists && !s.isIndex {
+			return []interface{}{v}
+		}
+	case []interface{}:
+		if s.wildcard {
+			return typed
+		}
+		if s.isIndex {
+			index := s.index
+			if index < 0 {
+				index += len(typed)
+			}
+			if index >= 0 && index < len(typed) {
+				return []interface{}{typed[index]}
+			}
+		}
+	}
+	return nil
+}
+
+// descendants returns value and all nested values, used for recursive descent
+func descendants(value interface{}) []interface{} {
+	values := []interface{}{value}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for _, v := range typed {
+			values = append(values, descendants(v)...)
+		}
+	case []interface{}:
+		for _, v := range typed {
+			values = append(values, descendants(v)...)
+		}
+	}
+	return values
+}
@@ -0,0 +1,114 @@
+package markers
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher holds the current marker list and reloads it whenever one of the markers files changes on disk.
+type Watcher struct {
+	files    []string
+	interval time.Duration
+	prepare  func([]string) []string
+	verbose  bool
+
+	current atomic.Value
+	states  map[string]fileState
+}
+
+func NewWatcher(files []string, initial []string, interval time.Duration, prepare func([]string) []string, verbose bool) *Watcher {
+	w := &Watcher{
+		files:    files,
+		interval: interval,
+		prepare:  prepare,
+		verbose:  verbose,
+		states:   make(map[string]fileState),
+	}
+	w.current.Store(initial)
+
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			w.states[file] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+
+	return w
+}
+
+// Markers returns the most recently loaded marker list. It is safe for concurrent use.
+func (w *Watcher) Markers() []string {
+	return w.current.Load().([]string)
+}
+
+// Watch polls the markers file until stop is closed.
+func (w *Watcher) Watch(stop <-chan struct{}) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.reloadIfChanged()
+		}
+	}
+}
+
+func (w *Watcher) reloadIfChanged() {
+	changed := false
+	newStates := make(map[string]fileState)
+
+	for _, file := range w.files {
+		info, err := os.Stat(file)
+		if err != nil {
+			if w.verbose {
+				log.Printf("Could not stat markers file %s: %v\n", file, err)
+			}
+			return
+		}
+
+		state := fileState{modTime: info.ModTime(), size: info.Size()}
+		if old, exists := w.states[file]; !exists || !old.modTime.Equal(state.modTime) || old.size != state.size {
+			changed = true
+		}
+		newStates[file] = state
+	}
+
+	if !changed {
+		return
+	}
+
+	var lines []string
+	for _, file := range w.files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Could not reload markers file %s: %v\n", file, err)
+			return
+		}
+
+		for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	if len(lines) == 0 {
+		// Most likely a file is being rewritten right now, keep the old markers
+		return
+	}
+
+	w.current.Store(w.prepare(lines))
+	w.states = newStates
+
+	log.Printf("Reloaded %d markers from %s\n", len(lines), strings.Join(w.files, ","))
+}
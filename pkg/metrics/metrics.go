@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Pipeline tracks where the scan pipeline waits: the generator on a full URL queue, workers on
+// an empty URL queue, workers on a full results queue, plus the number of requests in flight.
+type Pipeline struct {
+	generatorStall int64
+	workerIdle     int64
+	resultStall    int64
+	inFlight       int64
+}
+
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+func (p *Pipeline) AddGeneratorStall(d time.Duration) {
+	if p != nil {
+		atomic.AddInt64(&p.generatorStall, int64(d))
+	}
+}
+
+func (p *Pipeline) AddWorkerIdle(d time.Duration) {
+	if p != nil {
+		atomic.AddInt64(&p.workerIdle, int64(d))
+	}
+}
+
+func (p *Pipeline) AddResultStall(d time.Duration) {
+	if p != nil {
+		atomic.AddInt64(&p.resultStall, int64(d))
+	}
+}
+
+func (p *Pipeline) RequestStarted() {
+	if p != nil {
+		atomic.AddInt64(&p.inFlight, 1)
+	}
+}
+
+func (p *Pipeline) RequestFinished() {
+	if p != nil {
+		atomic.AddInt64(&p.inFlight, -1)
+	}
+}
+
+// Queue reports the current length and capacity of a queue
+type Queue func() (int, int)
+
+// Report logs the pipeline state every interval until ctx is cancelled
+func (p *Pipeline) Report(ctx context.Context, interval time.Duration, workers int, urls, results Queue) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastGenerator, lastIdle, lastResult int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		generator := atomic.LoadInt64(&p.generatorStall)
+		idle := atomic.LoadInt64(&p.workerIdle)
+		result := atomic.LoadInt64(&p.resultStall)
+
+		// Stall times relative to the interval, worker times summed over all workers
+		generatorShare := float64(generator-lastGenerator) / float64(interval)
+		idleShare := float64(idle-lastIdle) / float64(interval) / float64(workers)
+		resultShare := float64(result-lastResult) / float64(interval) / float64(workers)
+		lastGenerator, lastIdle, lastResult = generator, idle, result
+
+		urlLen, urlCap := urls()
+		resultLen, resultCap := results()
+
+		log.Printf("Pipeline: URL queue %d/%d | results queue %d/%d | in flight %d/%d | generator stalled %.0f%% | workers idle %.0f%% | workers blocked on results %.0f%% | bottleneck: %s\n",
+			urlLen, urlCap, resultLen, resultCap, atomic.LoadInt64(&p.inFlight), workers,
+			generatorShare*100, idleShare*100, resultShare*100, bottleneck(generatorShare, idleShare, resultShare))
+	}
+}
+
+func bottleneck(generatorShare, idleShare, resultShare float64) string {
+	switch {
+	case resultShare > 0.5:
+		return "result processing"
+	case idleShare > 0.5:
+		return "URL generation"
+	case generatorShare > 0.5:
+		return "network"
+	default:
+		return "none"
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package metrics
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func notifyStatusSignal(signals chan<- os.Signal) {
+	signal.Notify(signals, syscall.SIGUSR1)
+}
@@ -0,0 +1,6 @@
+package metrics
+
+import "os"
+
+// Windows has no SIGUSR1, the status is only available on the -status-socket
+func notifyStatusSignal(signals chan<- os.Signal) {}
@@ -0,0 +1,245 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+const (
+	// statusTopHosts limits the hosts of a snapshot to the ones with the most requests
+	statusTopHosts = 100
+	// statusMaxHosts bounds the hosts with their own counters, the requests of further hosts are
+	// counted for statusOtherHosts
+	statusMaxHosts   = 10000
+	statusOtherHosts = "other"
+)
+
+type hostCounters struct {
+	Host     string `json:"host"`
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"`
+	Findings int64  `json:"findings"`
+}
+
+// Snapshot is the machine-readable state of a running scan
+type Snapshot struct {
+	Timestamp  string           `json:"timestamp"`
+	Elapsed    float64          `json:"elapsed_seconds"`
+	Processed  int64            `json:"processed"`
+	Total      int64            `json:"total"`
+	Generating bool             `json:"generating"`
+	RPS        float64          `json:"rps"`
+	Findings   int64            `json:"findings"`
+	Errors     map[string]int64 `json:"errors"`
+	HostsTotal int              `json:"hosts_total"`
+	Hosts      []hostCounters   `json:"hosts"`
+}
+
+// Status collects the counters of a scan for snapshots, which are written to stderr on SIGUSR1
+// and served on a local socket
+type Status struct {
+	start      time.Time
+	processed  *int64
+	total      *int64
+	generating *int32
+	rps        uint64 // float64 bits, sampled every second
+
+	mu       sync.Mutex
+	findings int64
+	errors   map[string]int64
+	hosts    map[string]*hostCounters
+}
+
+// NewStatus reads the counters of the scan from processed and total, the total is final once
+// generating is zero
+func NewStatus(processed, total *int64, generating *int32) *Status {
+	return &Status{
+		start:      time.Now(),
+		processed:  processed,
+		total:      total,
+		generating: generating,
+		errors:     map[string]int64{"total": 0},
+		hosts:      make(map[string]*hostCounters),
+	}
+}
+
+// Observe counts a response of rawURL, err is its request error
+func (s *Status) Observe(rawURL string, err error) {
+	host := statusHost(rawURL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counters := s.host(host)
+	counters.Requests++
+	if err != nil {
+		counters.Errors++
+		s.errors["total"]++
+		s.errors[errorKind(err)]++
+	}
+}
+
+// RecordFinding counts a finding of rawURL
+func (s *Status) RecordFinding(rawURL string) {
+	host := statusHost(rawURL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.findings++
+	s.host(host).Findings++
+}
+
+func (s *Status) host(host string) *hostCounters {
+	counters, exists := s.hosts[host]
+	if !exists && len(s.hosts) >= statusMaxHosts {
+		host = statusOtherHosts
+		counters, exists = s.hosts[host]
+	}
+	if !exists {
+		counters = &hostCounters{Host: host}
+		s.hosts[host] = counters
+	}
+	return counters
+}
+
+func (s *Status) Snapshot() Snapshot {
+	now := time.Now()
+	snapshot := Snapshot{
+		Timestamp:  now.UTC().Format(time.RFC3339),
+		Elapsed:    now.Sub(s.start).Seconds(),
+		Processed:  atomic.LoadInt64(s.processed),
+		Total:      atomic.LoadInt64(s.total),
+		Generating: atomic.LoadInt32(s.generating) == 1,
+		RPS:        math.Float64frombits(atomic.LoadUint64(&s.rps)),
+		Errors:     make(map[string]int64),
+	}
+
+	s.mu.Lock()
+	snapshot.Findings = s.findings
+	for kind, count := range s.errors {
+		snapshot.Errors[kind] = count
+	}
+	snapshot.HostsTotal = len(s.hosts)
+	snapshot.Hosts = make([]hostCounters, 0, len(s.hosts))
+	for _, counters := range s.hosts {
+		snapshot.Hosts = append(snapshot.Hosts, *counters)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(snapshot.Hosts, func(i, j int) bool {
+		if snapshot.Hosts[i].Requests != snapshot.Hosts[j].Requests {
+			return snapshot.Hosts[i].Requests > snapshot.Hosts[j].Requests
+		}
+		return snapshot.Hosts[i].Host < snapshot.Hosts[j].Host
+	})
+	if len(snapshot.Hosts) > statusTopHosts {
+		snapshot.Hosts = snapshot.Hosts[:statusTopHosts]
+	}
+	return snapshot
+}
+
+// Write writes a snapshot as a single line of JSON to w
+func (s *Status) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s.Snapshot())
+}
+
+// Run samples the request rate every second and writes a snapshot to stderr on every SIGUSR1
+// until ctx is cancelled
+func (s *Status) Run(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	notifyStatusSignal(signals)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	lastProcessed, lastSample := atomic.LoadInt64(s.processed), time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			s.Write(os.Stderr)
+		case now := <-ticker.C:
+			processed := atomic.LoadInt64(s.processed)
+			rps := float64(processed-lastProcessed) / now.Sub(lastSample).Seconds()
+			atomic.StoreUint64(&s.rps, math.Float64bits(rps))
+			lastProcessed, lastSample = processed, now
+		}
+	}
+}
+
+// Serve writes a snapshot to every connection of the unix socket at path until ctx is cancelled
+func (s *Status) Serve(ctx context.Context, path string) error {
+	// A socket left behind by a crashed scan would block the listener, any other file is kept
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		s.Write(conn)
+		conn.Close()
+	}
+}
+
+func statusHost(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsed.Host
+}
+
+// errorKind classifies request errors for the error counts of a snapshot
+func errorKind(err error) string {
+	if errors.Is(err, result.ErrTarpit) {
+		return "tarpit"
+	}
+
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "timeout"), strings.Contains(message, "deadline exceeded"):
+		return "timeout"
+	case strings.Contains(message, "connection refused"):
+		return "connection_refused"
+	case strings.Contains(message, "connection reset"), strings.Contains(message, "broken pipe"), strings.Contains(message, "eof"):
+		return "connection_reset"
+	case strings.Contains(message, "no such host"), strings.Contains(message, "lookup"):
+		return "dns"
+	case strings.Contains(message, "tls"), strings.Contains(message, "x509"), strings.Contains(message, "certificate"):
+		return "tls"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,114 @@
+// Package openapi finds the Swagger or OpenAPI specification of a host and turns its documented
+// endpoints into paths to request.
+package openapi
+
+import (
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/checks"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"gopkg.in/yaml.v3"
+)
+
+// Path parameters like {id} are replaced by a value most APIs accept
+const parameterValue = "1"
+
+var parameterRegex = regexp.MustCompile(`\{[^}/]*\}`)
+
+type Client interface {
+	MakeRequest(url string) result.Result
+}
+
+// spec holds the fields of Swagger 2 and OpenAPI 3 documents needed to build the paths. YAML is
+// a superset of JSON, so both formats are parsed with the YAML decoder.
+type spec struct {
+	Swagger  string `yaml:"swagger"`
+	OpenAPI  string `yaml:"openapi"`
+	BasePath string `yaml:"basePath"`
+	Servers  []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	Paths map[string]interface{} `yaml:"paths"`
+}
+
+// Expander probes the specification locations of checks.OpenAPIPaths on every host
+type Expander struct {
+	client    Client
+	forceHTTP bool
+	verbose   bool
+}
+
+func NewExpander(client Client, forceHTTP, verbose bool) *Expander {
+	return &Expander{client: client, forceHTTP: forceHTTP, verbose: verbose}
+}
+
+// Paths returns the location of the first specification found on host followed by all paths it
+// documents, without the leading slash
+func (e *Expander) Paths(host string) []string {
+	base := e.baseURL(host)
+	for _, location := range checks.OpenAPIPaths {
+		res := e.client.MakeRequest(base + "/" + location)
+		if res.Error != nil || res.StatusCode != 200 {
+			continue
+		}
+
+		paths, ok := Parse(res.Content)
+		if !ok {
+			continue
+		}
+		if e.verbose {
+			log.Printf("OpenAPI: %d paths from %s/%s\n", len(paths), base, location)
+		}
+		return append([]string{location}, paths...)
+	}
+	return nil
+}
+
+func (e *Expander) baseURL(host string) string {
+	host = strings.TrimSuffix(host, "/")
+	if strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://") {
+		return host
+	}
+	if e.forceHTTP {
+		return "http://" + host
+	}
+	return "https://" + host
+}
+
+// Parse returns the documented paths of a Swagger 2 or OpenAPI 3 specification, prefixed with
+// its base path. ok is false if content is no specification.
+func Parse(content string) (paths []string, ok bool) {
+	var doc spec
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, false
+	}
+	if (doc.Swagger == "" && doc.OpenAPI == "") || len(doc.Paths) == 0 {
+		return nil, false
+	}
+
+	prefix := doc.BasePath
+	if len(doc.Servers) > 0 {
+		// Server URLs are absolute or relative to the specification, only the path is needed
+		if parsed, err := url.Parse(doc.Servers[0].URL); err == nil {
+			prefix = parsed.Path
+		}
+	}
+	prefix = strings.Trim(prefix, "/")
+
+	for path := range doc.Paths {
+		path = strings.Trim(parameterRegex.ReplaceAllString(path, parameterValue), "/")
+		if prefix != "" {
+			path = strings.Trim(prefix+"/"+path, "/")
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	// Map order is random, sorted paths keep the generation reproducible
+	sort.Strings(paths)
+	return paths, true
+}
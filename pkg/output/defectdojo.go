@@ -0,0 +1,126 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+// defectDojoFinding is a finding of the DefectDojo "Generic Findings Import" JSON format
+type defectDojoFinding struct {
+	Title            string   `json:"title"`
+	Description      string   `json:"description"`
+	Severity         string   `json:"severity"`
+	Mitigation       string   `json:"mitigation"`
+	Date             string   `json:"date"`
+	UniqueIDFromTool string   `json:"unique_id_from_tool"`
+	VulnIDFromTool   string   `json:"vuln_id_from_tool"`
+	Active           bool     `json:"active"`
+	Verified         bool     `json:"verified"`
+	StaticFinding    bool     `json:"static_finding"`
+	DynamicFinding   bool     `json:"dynamic_finding"`
+	Endpoints        []string `json:"endpoints"`
+	ComponentName    string   `json:"component_name,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+}
+
+// DefectDojoExporter collects the findings and writes them in the generic findings JSON format
+// accepted by the DefectDojo importer ("Generic Findings Import" scan type)
+type DefectDojoExporter struct {
+	filename string
+	mu       sync.Mutex
+	findings []defectDojoFinding
+}
+
+func NewDefectDojoExporter(filename string) *DefectDojoExporter {
+	return &DefectDojoExporter{filename: filename}
+}
+
+func (e *DefectDojoExporter) Add(finding result.Finding) {
+	// DefectDojo deduplicates reimports by this ID
+	sum := sha256.Sum256([]byte(finding.URL + "\x00" + finding.Detection))
+
+	converted := defectDojoFinding{
+		Title:            "Exposed file " + finding.URL,
+		Description:      defectDojoDescription(finding),
+		Severity:         DefectDojoSeverity(finding),
+		Mitigation:       "Remove the file from the web root or restrict the access to it.",
+		Date:             time.Now().Format("2006-01-02"),
+		UniqueIDFromTool: hex.EncodeToString(sum[:16]),
+		VulnIDFromTool:   finding.Detection,
+		Active:           true,
+		DynamicFinding:   true,
+		Endpoints:        []string{finding.URL},
+		Tags:             finding.Tags,
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.findings = append(e.findings, converted)
+}
+
+// DefectDojoSeverity maps the detection of finding to a DefectDojo severity. Leaked secrets and
+// public buckets are high, source code and marker hits medium, matches of the rules alone low
+// and mere existence checks informational.
+func DefectDojoSeverity(finding result.Finding) string {
+	detection := finding.Detection
+	switch {
+	case strings.HasPrefix(detection, "entropy:"), strings.HasSuffix(detection, "-public-listing"):
+		return "High"
+	case strings.Contains(detection, ":exists"), strings.Contains(detection, "-exists"):
+		return "Info"
+	case detection == "rules":
+		return "Low"
+	default:
+		return "Medium"
+	}
+}
+
+func defectDojoDescription(finding result.Finding) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dynamic-file-searcher found %s.\n\n", finding.URL)
+	fmt.Fprintf(&b, "**Detection:** %s\n\n", finding.Detection)
+	if finding.Marker != "" {
+		fmt.Fprintf(&b, "**Marker:** `%s`\n\n", result.MarkerPattern(finding.Marker))
+	}
+	fmt.Fprintf(&b, "**Status:** %d\n\n", finding.StatusCode)
+	fmt.Fprintf(&b, "**Size:** %d bytes\n", finding.FileSize)
+	if finding.ContentType != "" {
+		fmt.Fprintf(&b, "\n**Content type:** %s\n", finding.ContentType)
+	}
+	if finding.Title != "" {
+		fmt.Fprintf(&b, "\n**Title:** %s\n", finding.Title)
+	}
+	if finding.SHA256 != "" {
+		fmt.Fprintf(&b, "\n**SHA-256:** %s\n", finding.SHA256)
+	}
+	if finding.Server != "" {
+		fmt.Fprintf(&b, "\n**Server:** %s\n", finding.Server)
+	}
+	return b.String()
+}
+
+// Close writes the collected findings
+func (e *DefectDojoExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	findings := e.findings
+	if findings == nil {
+		findings = []defectDojoFinding{}
+	}
+	data, err := json.MarshalIndent(struct {
+		Findings []defectDojoFinding `json:"findings"`
+	}{findings}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(e.filename, append(data, '\n'), 0644)
+}
@@ -0,0 +1,121 @@
+package output
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// kafkaTimeout bounds the metadata check of NewKafkaPublisher and every publish
+const kafkaTimeout = 30 * time.Second
+
+type kafkaFinding struct {
+	URL         string              `json:"url"`
+	Detection   string              `json:"detection"`
+	Marker      string              `json:"marker,omitempty"`
+	StatusCode  int                 `json:"status"`
+	FileSize    int64               `json:"size"`
+	ContentType string              `json:"content_type,omitempty"`
+	SHA256      string              `json:"sha256,omitempty"`
+	Title       string              `json:"title,omitempty"`
+	FaviconHash string              `json:"favicon_hash,omitempty"`
+	Server      string              `json:"server,omitempty"`
+	Certificate *result.Certificate `json:"certificate,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Timestamp   string              `json:"timestamp"`
+}
+
+// KafkaPublisher publishes every finding as a JSON record to a Kafka topic, keyed by the URL so
+// all findings of a URL land in the same partition
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher checks that the brokers know topic. mechanism is empty, plain, scram-sha-256
+// or scram-sha-512, the credentials are only used with a mechanism.
+func NewKafkaPublisher(brokers []string, topic string, useTLS bool, mechanism, user, password string) (*KafkaPublisher, error) {
+	transport := &kafka.Transport{ClientID: "dynamic-file-searcher"}
+	if useTLS {
+		transport.TLS = &tls.Config{}
+	}
+	if mechanism != "" {
+		var err error
+		if transport.SASL, err = kafkaSASL(mechanism, user, password); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+	client := &kafka.Client{Addr: kafka.TCP(brokers...), Transport: transport}
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata.Topics) != 1 || metadata.Topics[0].Error != nil {
+		if len(metadata.Topics) == 1 {
+			return nil, fmt.Errorf("topic %s: %w", topic, metadata.Topics[0].Error)
+		}
+		return nil, fmt.Errorf("topic %s not found", topic)
+	}
+
+	return &KafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+		// Findings are published one at a time as they are found
+		BatchSize:    1,
+		WriteTimeout: kafkaTimeout,
+		Transport:    transport,
+	}}, nil
+}
+
+func kafkaSASL(mechanism, user, password string) (sasl.Mechanism, error) {
+	switch mechanism {
+	case "plain":
+		return plain.Mechanism{Username: user, Password: password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, user, password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, user, password)
+	}
+	return nil, fmt.Errorf("unsupported SASL mechanism %s", mechanism)
+}
+
+func (p *KafkaPublisher) Publish(finding result.Finding) error {
+	data, err := json.Marshal(kafkaFinding{
+		URL:         finding.URL,
+		Detection:   finding.Detection,
+		Marker:      finding.Marker,
+		StatusCode:  finding.StatusCode,
+		FileSize:    finding.FileSize,
+		ContentType: finding.ContentType,
+		SHA256:      finding.SHA256,
+		Title:       finding.Title,
+		FaviconHash: finding.FaviconHash,
+		Server:      finding.Server,
+		Certificate: finding.Certificate,
+		Tags:        finding.Tags,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+	return p.writer.WriteMessages(ctx, kafka.Message{Key: []byte(finding.URL), Value: data})
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,102 @@
+package output
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"github.com/fatih/color"
+)
+
+// Monitor compares the findings of the iterations of -monitor with the ones of the previous
+// iteration, so only the changes have to be looked at.
+type Monitor struct {
+	mu        sync.Mutex
+	iteration int
+	// previous holds the findings of the last complete iteration by URL, nil before the first
+	previous map[string]result.Finding
+	current  map[string]result.Finding
+	// known holds the URLs found by any earlier iteration, aborted ones included
+	known map[string]bool
+}
+
+func NewMonitor() *Monitor {
+	return &Monitor{known: make(map[string]bool)}
+}
+
+// Start begins the next iteration and returns its number
+func (m *Monitor) Start() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.iteration++
+	m.current = make(map[string]result.Finding)
+	return m.iteration
+}
+
+// Add records a finding of the current iteration and reports whether it is new, i.e. none of the
+// earlier iterations found its URL. Only new findings are sent to the notification integrations.
+func (m *Monitor) Add(finding result.Finding) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.current[finding.URL] = finding
+	return !m.known[finding.URL]
+}
+
+// Known reports whether an earlier iteration found rawURL
+func (m *Monitor) Known(rawURL string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.known[rawURL]
+}
+
+// Unchanged keeps the finding of the previous iteration for a URL whose response did not change
+func (m *Monitor) Unchanged(rawURL string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if finding, ok := m.previous[rawURL]; ok {
+		m.current[rawURL] = finding
+	}
+}
+
+// Finish prints the new and gone findings of the iteration. The findings of an aborted iteration
+// are incomplete, it is not compared with the previous one.
+func (m *Monitor) Finish(aborted bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The findings of an aborted iteration were alerted as well
+	for rawURL := range m.current {
+		m.known[rawURL] = true
+	}
+	if aborted {
+		return
+	}
+	if m.previous == nil {
+		color.Cyan("\n[i] Monitoring iteration %d: %d findings, the next iterations list the changes", m.iteration, len(m.current))
+		m.previous = m.current
+		return
+	}
+
+	var added, gone []string
+	for rawURL := range m.current {
+		if _, ok := m.previous[rawURL]; !ok {
+			added = append(added, rawURL)
+		}
+	}
+	for rawURL := range m.previous {
+		if _, ok := m.current[rawURL]; !ok {
+			gone = append(gone, rawURL)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(gone)
+
+	color.Cyan("\n[i] Monitoring iteration %d: %d findings, %d new, %d gone", m.iteration, len(m.current), len(added), len(gone))
+	for _, rawURL := range added {
+		color.Green("    + %s (%s)", rawURL, m.current[rawURL].Detection)
+	}
+	for _, rawURL := range gone {
+		color.Yellow("    - %s (%s)", rawURL, m.previous[rawURL].Detection)
+	}
+	m.previous = m.current
+}
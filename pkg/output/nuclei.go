@@ -0,0 +1,177 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"gopkg.in/yaml.v3"
+)
+
+var templateIDRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+type nucleiTemplate struct {
+	ID   string          `yaml:"id"`
+	Info nucleiInfo      `yaml:"info"`
+	HTTP []nucleiRequest `yaml:"http"`
+}
+
+type nucleiInfo struct {
+	Name        string `yaml:"name"`
+	Author      string `yaml:"author"`
+	Severity    string `yaml:"severity"`
+	Description string `yaml:"description"`
+	Tags        string `yaml:"tags"`
+}
+
+type nucleiRequest struct {
+	Method            string            `yaml:"method"`
+	Path              []string          `yaml:"path"`
+	Headers           map[string]string `yaml:"headers,omitempty"`
+	MatchersCondition string            `yaml:"matchers-condition"`
+	Matchers          []nucleiMatcher   `yaml:"matchers"`
+}
+
+type nucleiMatcher struct {
+	Type            string   `yaml:"type"`
+	Part            string   `yaml:"part,omitempty"`
+	Status          []int    `yaml:"status,omitempty"`
+	Words           []string `yaml:"words,omitempty"`
+	Regex           []string `yaml:"regex,omitempty"`
+	CaseInsensitive bool     `yaml:"case-insensitive,omitempty"`
+}
+
+// NucleiExporter writes a nuclei template reproducing the request and matcher of every finding
+// to dir/templates and the base URLs of all findings to dir/targets.txt, so findings can be
+// re-verified with `nuclei -l targets.txt -t templates/`.
+type NucleiExporter struct {
+	dir          string
+	headers      map[string]string
+	ignoreCase   bool
+	mu           sync.Mutex
+	targets      map[string]struct{}
+	templateKeys map[string]struct{}
+}
+
+func NewNucleiExporter(dir string, headers map[string]string, ignoreCase bool) (*NucleiExporter, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "templates"), 0755); err != nil {
+		return nil, err
+	}
+
+	return &NucleiExporter{
+		dir:          dir,
+		headers:      headers,
+		ignoreCase:   ignoreCase,
+		targets:      make(map[string]struct{}),
+		templateKeys: make(map[string]struct{}),
+	}, nil
+}
+
+// Add writes the template of finding
+func (e *NucleiExporter) Add(finding result.Finding) error {
+	parsed, err := url.Parse(finding.URL)
+	if err != nil {
+		return err
+	}
+	target := parsed.Scheme + "://" + parsed.Host
+
+	template := e.template(finding, parsed)
+	data, err := yaml.Marshal(template)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.targets[target] = struct{}{}
+	if _, exists := e.templateKeys[template.ID]; exists {
+		return nil
+	}
+	e.templateKeys[template.ID] = struct{}{}
+
+	return os.WriteFile(filepath.Join(e.dir, "templates", template.ID+".yaml"), data, 0644)
+}
+
+func (e *NucleiExporter) template(finding result.Finding, parsed *url.URL) nucleiTemplate {
+	path := parsed.EscapedPath()
+	if parsed.RawQuery != "" {
+		path += "?" + parsed.RawQuery
+	}
+
+	matchers := []nucleiMatcher{{Type: "status", Status: []int{finding.StatusCode}}}
+	if matcher, ok := e.bodyMatcher(finding.Marker); ok {
+		matchers = append(matchers, matcher)
+	}
+
+	// The ID is derived from the finding, so repeated exports overwrite instead of duplicating templates
+	sum := sha256.Sum256([]byte(finding.URL + "\x00" + finding.Detection))
+	id := strings.Trim(templateIDRegex.ReplaceAllString(strings.ToLower(parsed.Host+"-"+parsed.Path), "-"), "-")
+	if len(id) > 60 {
+		id = id[:60]
+	}
+	id = "dfs-" + id + "-" + hex.EncodeToString(sum[:4])
+
+	return nucleiTemplate{
+		ID: id,
+		Info: nucleiInfo{
+			Name:        "Exposed file " + parsed.Path,
+			Author:      "dynamic-file-searcher",
+			Severity:    "info",
+			Description: fmt.Sprintf("%s was found by dynamic-file-searcher (detection: %s)", finding.URL, finding.Detection),
+			Tags:        "dfs,exposure",
+		},
+		HTTP: []nucleiRequest{{
+			Method:            "GET",
+			Path:              []string{"{{BaseURL}}" + path},
+			Headers:           e.headers,
+			MatchersCondition: "and",
+			Matchers:          matchers,
+		}},
+	}
+}
+
+// bodyMatcher converts a marker into a nuclei body matcher, jsonpath markers have no equivalent
+func (e *NucleiExporter) bodyMatcher(marker string) (nucleiMatcher, bool) {
+	pattern := result.MarkerPattern(marker)
+	switch {
+	case pattern == "" || strings.HasPrefix(pattern, "jsonpath:"):
+		return nucleiMatcher{}, false
+	case strings.HasPrefix(pattern, "regex:"):
+		expression := strings.TrimPrefix(pattern, "regex:")
+		if e.ignoreCase {
+			expression = "(?i)" + expression
+		}
+		return nucleiMatcher{Type: "regex", Part: "body", Regex: []string{expression}}, true
+	case strings.HasPrefix(pattern, "entropy:"):
+		return nucleiMatcher{Type: "word", Part: "body", Words: []string{strings.TrimPrefix(pattern, "entropy:")}}, true
+	default:
+		return nucleiMatcher{Type: "word", Part: "body", Words: []string{pattern}, CaseInsensitive: e.ignoreCase}, true
+	}
+}
+
+// Close writes the target list
+func (e *NucleiExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	targets := make([]string, 0, len(e.targets))
+	for target := range e.targets {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	var content string
+	if len(targets) > 0 {
+		content = strings.Join(targets, "\n") + "\n"
+	}
+	return os.WriteFile(filepath.Join(e.dir, "targets.txt"), []byte(content), 0644)
+}
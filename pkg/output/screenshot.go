@@ -0,0 +1,156 @@
+package output
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// screenshotQueueSize bounds the findings waiting for a screenshot, further ones are dropped
+const screenshotQueueSize = 1000
+
+// Browser binaries looked up in PATH if no browser was configured
+var browserNames = []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome"}
+
+// Screenshotter captures screenshots of finding URLs with headless Chrome into dir. It has its own
+// pool of workers, Add never blocks the scan.
+type Screenshotter struct {
+	dir      string
+	browser  string
+	timeout  time.Duration
+	proxyURL *url.URL
+	// noSandbox disables the Chrome sandbox, the scanned pages are untrusted
+	noSandbox bool
+	verbose   bool
+
+	queue   chan string
+	wg      sync.WaitGroup
+	dropped int64
+}
+
+// NewScreenshotter fails if browser is empty and no Chrome or Chromium is found in PATH
+func NewScreenshotter(dir, browser string, workers int, timeout time.Duration, proxyURL *url.URL, noSandbox, verbose bool) (*Screenshotter, error) {
+	if browser == "" {
+		for _, name := range browserNames {
+			if path, err := exec.LookPath(name); err == nil {
+				browser = path
+				break
+			}
+		}
+		if browser == "" {
+			return nil, fmt.Errorf("no Chrome or Chromium found in PATH (tried %s)", strings.Join(browserNames, ", "))
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &Screenshotter{
+		dir:       dir,
+		browser:   browser,
+		timeout:   timeout,
+		proxyURL:  proxyURL,
+		noSandbox: noSandbox,
+		verbose:   verbose,
+		queue:     make(chan string, screenshotQueueSize),
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+
+	return s, nil
+}
+
+// Add queues a screenshot of rawURL, it is dropped if the queue is full
+func (s *Screenshotter) Add(rawURL string) {
+	select {
+	case s.queue <- rawURL:
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+func (s *Screenshotter) work() {
+	defer s.wg.Done()
+
+	for rawURL := range s.queue {
+		if err := s.capture(rawURL); err != nil && s.verbose {
+			log.Printf("Could not take a screenshot of %s: %v\n", rawURL, err)
+		}
+	}
+}
+
+func (s *Screenshotter) capture(rawURL string) error {
+	// Parallel browser instances need their own profile
+	profile, err := os.MkdirTemp("", "dfs-screenshot-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(profile)
+
+	target := filepath.Join(s.dir, screenshotName(rawURL))
+	args := []string{
+		"--headless",
+		"--disable-gpu",
+		"--hide-scrollbars",
+		"--ignore-certificate-errors",
+		"--window-size=1280,800",
+		"--user-data-dir=" + profile,
+		"--screenshot=" + target,
+	}
+	if s.noSandbox {
+		args = append(args, "--no-sandbox")
+	}
+	if s.proxyURL != nil {
+		args = append(args, "--proxy-server="+s.proxyURL.String())
+	}
+	args = append(args, rawURL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	if output, err := exec.CommandContext(ctx, s.browser, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	if _, err := os.Stat(target); err != nil {
+		return fmt.Errorf("the browser wrote no screenshot")
+	}
+	return nil
+}
+
+// screenshotName derives a readable, unique file name from rawURL
+func screenshotName(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	name := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil {
+		name = parsed.Host + parsed.Path
+	}
+	name = strings.Trim(templateIDRegex.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	return name + "-" + hex.EncodeToString(sum[:4]) + ".png"
+}
+
+// Close waits for the queued screenshots and returns the number of dropped ones
+func (s *Screenshotter) Close() int64 {
+	close(s.queue)
+	s.wg.Wait()
+	return atomic.LoadInt64(&s.dropped)
+}
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+type storedResponse struct {
+	URL          string              `json:"url"`
+	StatusCode   int                 `json:"status"`
+	FileSize     int64               `json:"size"`
+	ContentType  string              `json:"content_type"`
+	DurationMs   int64               `json:"duration_ms"`
+	SoftNotFound bool                `json:"soft_404,omitempty"`
+	FaviconHash  string              `json:"favicon_hash,omitempty"`
+	Server       string              `json:"server,omitempty"`
+	Blocked      bool                `json:"blocked,omitempty"`
+	WAF          string              `json:"waf,omitempty"`
+	Certificate  *result.Certificate `json:"certificate,omitempty"`
+	Tags         []string            `json:"tags,omitempty"`
+	ETag         string              `json:"etag,omitempty"`
+	LastModified string              `json:"last_modified,omitempty"`
+	Unchanged    bool                `json:"unchanged,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// StoreAllWriter writes the metadata of every response as one JSON object per line.
+type StoreAllWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func NewStoreAllWriter(filename string) (*StoreAllWriter, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(file)
+	return &StoreAllWriter{
+		file:    file,
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}, nil
+}
+
+func (w *StoreAllWriter) Write(res result.Result) error {
+	record := storedResponse{
+		URL:          res.URL,
+		StatusCode:   res.StatusCode,
+		FileSize:     res.FileSize,
+		ContentType:  res.ContentType,
+		DurationMs:   res.Duration.Milliseconds(),
+		SoftNotFound: res.SoftNotFound,
+		FaviconHash:  res.FaviconHash,
+		Server:       res.Server,
+		Blocked:      res.Blocked,
+		WAF:          res.WAF,
+		Certificate:  res.Certificate,
+		Tags:         res.Tags,
+		ETag:         res.ETag,
+		LastModified: res.LastModified,
+		Unchanged:    res.Unchanged,
+	}
+	if res.Error != nil {
+		record.Error = res.Error.Error()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.encoder.Encode(record)
+}
+
+func (w *StoreAllWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writer.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
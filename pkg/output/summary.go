@@ -0,0 +1,69 @@
+package output
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"github.com/fatih/color"
+)
+
+// Summary collects findings during the scan to print them grouped by detection at the end.
+type Summary struct {
+	mu     sync.Mutex
+	groups map[string][]string
+	// suspects holds the hosts flagged as wildcard responders
+	suspects map[string]bool
+}
+
+func NewSummary() *Summary {
+	return &Summary{groups: make(map[string][]string), suspects: make(map[string]bool)}
+}
+
+// MarkSuspect marks the findings of host (with port) as suspect
+func (s *Summary) MarkSuspect(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.suspects[host] = true
+}
+
+func (s *Summary) Add(finding result.Finding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups[finding.Detection] = append(s.groups[finding.Detection], finding.URL)
+}
+
+// Print lists all detections, the ones with the most findings first
+func (s *Summary) Print() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.groups) == 0 {
+		return
+	}
+
+	detections := make([]string, 0, len(s.groups))
+	for detection := range s.groups {
+		detections = append(detections, detection)
+	}
+	sort.Slice(detections, func(i, j int) bool {
+		if len(s.groups[detections[i]]) != len(s.groups[detections[j]]) {
+			return len(s.groups[detections[i]]) > len(s.groups[detections[j]])
+		}
+		return detections[i] < detections[j]
+	})
+
+	color.Cyan("\n[i] Findings by detection:")
+	for _, detection := range detections {
+		urls := s.groups[detection]
+		color.Cyan("\n  %s (%d)", detection, len(urls))
+		for _, rawURL := range urls {
+			if parsed, err := url.Parse(rawURL); err == nil && s.suspects[parsed.Host] {
+				color.Yellow("    %s (suspect: wildcard responder)", rawURL)
+				continue
+			}
+			color.White("    %s", rawURL)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package output
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+const (
+	// local0.warning
+	syslogPriority = 16*8 + 4
+	syslogAppName  = "dynamic-file-searcher"
+	// Private enterprise number for documentation (RFC 5612)
+	syslogSDID        = "finding@32473"
+	syslogDialTimeout = 10 * time.Second
+)
+
+var sdValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// SyslogWriter sends every finding as an RFC 5424 message to a syslog server. TCP messages use
+// octet counting framing (RFC 6587), the connection is re-established once if a write fails.
+type SyslogWriter struct {
+	network  string
+	address  string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogWriter connects to addr, which is host:port (UDP) or udp://host:port or tcp://host:port
+func NewSyslogWriter(addr string) (*SyslogWriter, error) {
+	network, address := "udp", addr
+	if i := strings.Index(addr, "://"); i >= 0 {
+		network, address = addr[:i], addr[i+3:]
+	}
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("unsupported syslog protocol '%s', use udp or tcp", network)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	w := &SyslogWriter{network: network, address: address, hostname: hostname}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *SyslogWriter) connect() error {
+	conn, err := net.DialTimeout(w.network, w.address, syslogDialTimeout)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *SyslogWriter) Write(finding result.Finding) error {
+	message := w.format(finding, time.Now())
+	if w.network == "tcp" {
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.conn.Write([]byte(message)); err != nil {
+		w.conn.Close()
+		if err := w.connect(); err != nil {
+			return err
+		}
+		_, err = w.conn.Write([]byte(message))
+		return err
+	}
+	return nil
+}
+
+// format returns the RFC 5424 message of finding, its details are structured data
+func (w *SyslogWriter) format(finding result.Finding, now time.Time) string {
+	params := []string{
+		sdParam("url", finding.URL),
+		sdParam("detection", finding.Detection),
+		sdParam("status", fmt.Sprint(finding.StatusCode)),
+		sdParam("size", fmt.Sprint(finding.FileSize)),
+	}
+	if finding.ContentType != "" {
+		params = append(params, sdParam("content_type", finding.ContentType))
+	}
+	if finding.Title != "" {
+		params = append(params, sdParam("title", finding.Title))
+	}
+	if finding.SHA256 != "" {
+		params = append(params, sdParam("sha256", finding.SHA256))
+	}
+	if finding.FaviconHash != "" {
+		params = append(params, sdParam("favicon_hash", finding.FaviconHash))
+	}
+	if len(finding.Tags) > 0 {
+		params = append(params, sdParam("tags", strings.Join(finding.Tags, ";")))
+	}
+	if finding.Server != "" {
+		params = append(params, sdParam("server", finding.Server))
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d finding [%s %s] Match found in %s",
+		syslogPriority, now.UTC().Format(time.RFC3339), w.hostname, syslogAppName, os.Getpid(),
+		syslogSDID, strings.Join(params, " "), finding.URL)
+}
+
+func sdParam(name, value string) string {
+	return name + `="` + sdValueEscaper.Replace(value) + `"`
+}
+
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn.Close()
+}
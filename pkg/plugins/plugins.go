@@ -0,0 +1,36 @@
+// Package plugins loads the Go plugins of -analyzer-plugin. It is kept apart from the result
+// package, so only the binaries which support the flag link the dynamic loader.
+package plugins
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+// analyzerSymbol is the name of the exported variable a Go plugin must provide
+const analyzerSymbol = "Analyzer"
+
+// LoadAnalyzer opens a Go plugin (built with -buildmode=plugin) and returns its exported Analyzer
+// variable
+func LoadAnalyzer(path string) (result.Analyzer, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	symbol, err := p.Lookup(analyzerSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	switch analyzer := symbol.(type) {
+	case *result.Analyzer:
+		return *analyzer, nil
+	case result.Analyzer:
+		return analyzer, nil
+	default:
+		return nil, fmt.Errorf("symbol %s in %s does not implement result.Analyzer", analyzerSymbol, path)
+	}
+}
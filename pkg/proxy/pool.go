@@ -0,0 +1,129 @@
+// Package proxy rotates the requests over a list of proxies and takes the proxies which fail their
+// health checks out of the rotation.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout is the time a proxy has to accept the connection of a health check
+const checkTimeout = 5 * time.Second
+
+// ErrAllDown is returned for requests while none of the proxies passed its last health check
+var ErrAllDown = errors.New("all proxies are down")
+
+// Pool hands out the proxies round-robin. Until the first health check all proxies are used.
+type Pool struct {
+	proxies []*url.URL
+	verbose bool
+
+	mu    sync.RWMutex
+	alive []*url.URL
+	down  map[string]bool
+	next  uint32
+}
+
+func NewPool(proxies []*url.URL, verbose bool) *Pool {
+	return &Pool{
+		proxies: proxies,
+		verbose: verbose,
+		alive:   proxies,
+		down:    make(map[string]bool),
+	}
+}
+
+// Len returns the number of proxies of the pool
+func (p *Pool) Len() int {
+	return len(p.proxies)
+}
+
+// Proxy returns the proxy of the next request, it is used as http.Transport.Proxy
+func (p *Pool) Proxy(*http.Request) (*url.URL, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.alive) == 0 {
+		return nil, ErrAllDown
+	}
+	i := atomic.AddUint32(&p.next, 1)
+	return p.alive[int(i)%len(p.alive)], nil
+}
+
+// Check connects to all proxies at once, takes the ones which do not accept the connection out of
+// the rotation and puts recovered ones back. It returns the number of proxies which are up.
+func (p *Pool) Check() int {
+	up := make([]bool, len(p.proxies))
+	var wg sync.WaitGroup
+	for i, proxy := range p.proxies {
+		wg.Add(1)
+		go func(i int, proxy *url.URL) {
+			defer wg.Done()
+			conn, err := net.DialTimeout("tcp", address(proxy), checkTimeout)
+			if err != nil {
+				return
+			}
+			conn.Close()
+			up[i] = true
+		}(i, proxy)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	alive := make([]*url.URL, 0, len(p.proxies))
+	for i, proxy := range p.proxies {
+		if up[i] {
+			alive = append(alive, proxy)
+		}
+		if p.verbose && up[i] == p.down[proxy.String()] {
+			if up[i] {
+				log.Printf("Proxy %s is up again\n", proxy.Redacted())
+			} else {
+				log.Printf("Proxy %s is down, it is taken out of the rotation\n", proxy.Redacted())
+			}
+		}
+		p.down[proxy.String()] = !up[i]
+	}
+	p.alive = alive
+	return len(alive)
+}
+
+// Run checks the proxies every interval until ctx is cancelled. onAllDown is called after every
+// check none of the proxies passed.
+func (p *Pool) Run(ctx context.Context, interval time.Duration, onAllDown func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if p.Check() == 0 {
+				onAllDown()
+			}
+		}
+	}
+}
+
+// address returns host:port of proxy with the default port of its scheme
+func address(proxy *url.URL) string {
+	if proxy.Port() != "" {
+		return proxy.Host
+	}
+	port := "80"
+	switch proxy.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(proxy.Hostname(), port)
+}
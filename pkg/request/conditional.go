@@ -0,0 +1,94 @@
+package request
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Validators are the ETag and Last-Modified headers of an earlier response of a URL
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Conditions holds the validators of the URLs of an earlier scan, their requests send
+// If-None-Match and If-Modified-Since so unchanged responses are answered with a bodiless 304. A nil
+// *Conditions holds none.
+type Conditions struct {
+	mu   sync.RWMutex
+	urls map[string]Validators
+}
+
+func NewConditions() *Conditions {
+	return &Conditions{urls: make(map[string]Validators)}
+}
+
+// storedValidators are the fields of a -store-all record the conditions are read from
+type storedValidators struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+	Error        string `json:"error"`
+}
+
+// ReadConditions reads the validators of the -store-all records in filename
+func ReadConditions(filename string) (*Conditions, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	c := NewConditions()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record storedValidators
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Error == "" && (record.ETag != "" || record.LastModified != "") {
+			c.urls[record.URL] = Validators{ETag: record.ETag, LastModified: record.LastModified}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Len returns the number of URLs with validators
+func (c *Conditions) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.urls)
+}
+
+// Lookup returns the validators of rawURL
+func (c *Conditions) Lookup(rawURL string) (Validators, bool) {
+	if c == nil {
+		return Validators{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.urls[rawURL]
+	return v, ok
+}
+
+// Update replaces the validators of rawURL, e.g. with the ones of the last iteration of -monitor
+func (c *Conditions) Update(rawURL string, v Validators) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.urls[rawURL] = v
+}
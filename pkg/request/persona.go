@@ -0,0 +1,110 @@
+package request
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+const (
+	chromeAccept  = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"
+	firefoxAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+	safariAccept  = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+)
+
+// Persona is a browser on a platform. Its headers match each other, e.g. only Chromium based
+// browsers send the sec-ch-ua client hints and their platform is the one of the User-Agent.
+type Persona struct {
+	Name    string
+	headers [][2]string
+}
+
+// Headers returns the headers of the persona in the order the browser sends them
+func (p Persona) Headers() [][2]string {
+	return p.headers
+}
+
+func chromium(name, userAgent, brand, platform string, mobile bool) Persona {
+	mobileHint := "?0"
+	if mobile {
+		mobileHint = "?1"
+	}
+	return Persona{Name: name, headers: [][2]string{
+		{"sec-ch-ua", fmt.Sprintf(`"Chromium";v="124", "%s";v="124", "Not-A.Brand";v="99"`, brand)},
+		{"sec-ch-ua-mobile", mobileHint},
+		{"sec-ch-ua-platform", `"` + platform + `"`},
+		{"Upgrade-Insecure-Requests", "1"},
+		{"User-Agent", userAgent},
+		{"Accept", chromeAccept},
+		{"Accept-Language", "en-US,en;q=0.9"},
+	}}
+}
+
+func browser(name, userAgent, accept, acceptLanguage string) Persona {
+	return Persona{Name: name, headers: [][2]string{
+		{"User-Agent", userAgent},
+		{"Accept", accept},
+		{"Accept-Language", acceptLanguage},
+		{"Upgrade-Insecure-Requests", "1"},
+	}}
+}
+
+var personas = []Persona{
+	chromium("chrome-windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36", "Google Chrome", "Windows", false),
+	chromium("chrome-macos", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36", "Google Chrome", "macOS", false),
+	chromium("chrome-android", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36", "Google Chrome", "Android", true),
+	chromium("edge-windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0", "Microsoft Edge", "Windows", false),
+	browser("firefox-windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0", firefoxAccept, "en-US,en;q=0.5"),
+	browser("firefox-linux", "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0", firefoxAccept, "en-US,en;q=0.5"),
+	browser("safari-macos", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15", safariAccept, "en-US,en;q=0.9"),
+	browser("safari-ios", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1", safariAccept, "en-US,en;q=0.9"),
+}
+
+// PersonaNames returns the names of all personas, sorted
+func PersonaNames() []string {
+	names := make([]string, 0, len(personas))
+	for _, p := range personas {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParsePersonas returns the personas of a comma separated list of names, "rotate" selects all
+func ParsePersonas(value string) ([]Persona, error) {
+	if strings.TrimSpace(value) == "rotate" {
+		return personas, nil
+	}
+
+	var selected []Persona
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, p := range personas {
+			if p.Name == name {
+				selected = append(selected, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown persona '%s', available: rotate, %s", name, strings.Join(PersonaNames(), ", "))
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no persona given")
+	}
+	return selected, nil
+}
+
+// RandomPersona picks the persona of a request from selected, all personas if it is empty
+func RandomPersona(selected []Persona) Persona {
+	if len(selected) == 0 {
+		selected = personas
+	}
+	return selected[rand.Intn(len(selected))]
+}
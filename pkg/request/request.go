@@ -0,0 +1,70 @@
+// Package request describes requests which replace the default GET of a URL, e.g. the POST of a
+// GraphQL introspection query or the JSON bodies of -api-mode, the browser personas whose headers
+// are sent with every request and the validators of conditional requests.
+package request
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Spec is the request sent instead of a GET
+type Spec struct {
+	Method  string
+	Headers map[string]string
+	Body    []byte
+}
+
+type pathSpec struct {
+	path string
+	spec Spec
+}
+
+// Overrides maps URL paths to the request sent for them. A nil *Overrides overrides nothing.
+type Overrides struct {
+	mu    sync.RWMutex
+	paths []pathSpec
+	// templates are the JSON bodies of -api-mode
+	templates []string
+}
+
+func NewOverrides() *Overrides {
+	return &Overrides{}
+}
+
+// AddPath sends spec for all URLs whose path ends with /path
+func (o *Overrides) AddPath(path string, spec Spec) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.paths = append(o.paths, pathSpec{path: "/" + strings.TrimPrefix(path, "/"), spec: spec})
+}
+
+// Lookup returns the request for rawURL, the first matching path wins
+func (o *Overrides) Lookup(rawURL string) (Spec, bool) {
+	if o == nil {
+		return Spec{}, false
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if len(o.paths) == 0 && len(o.templates) == 0 {
+		return Spec{}, false
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return Spec{}, false
+	}
+	if parsed.Fragment != "" {
+		if spec, ok := o.templateSpec(parsed); ok {
+			return spec, true
+		}
+	}
+	for _, p := range o.paths {
+		if strings.HasSuffix(parsed.Path, p.path) {
+			return p.spec, true
+		}
+	}
+	return Spec{}, false
+}
@@ -0,0 +1,87 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templateFragment selects the body template of a URL. Fragments are not sent, so the URL still
+// requests the generated path.
+const templateFragment = "api-body-"
+
+// DefaultTemplates are sent with -api-mode if no template file is given. JSON backends usually
+// answer an empty object with a validation error listing the expected fields.
+var DefaultTemplates = []string{"{}"}
+
+var apiPathRegex = regexp.MustCompile(`(?i)(^|/)(api|rest|graphql|rpc|v\d+)(/|$)|\.json$`)
+
+// IsAPIPath reports whether path looks like an API endpoint, e.g. /api/users or /v2/orders
+func IsAPIPath(path string) bool {
+	return apiPathRegex.MatchString(path)
+}
+
+// TemplateURLs returns a URL for every template and API-looking URL of urls, the GET of the URLs
+// themselves is not included
+func TemplateURLs(urls []string, templates int) []string {
+	var templateURLs []string
+	for _, rawURL := range urls {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || !IsAPIPath(parsed.Path) {
+			continue
+		}
+		for i := 1; i <= templates; i++ {
+			templateURLs = append(templateURLs, rawURL+"#"+templateFragment+strconv.Itoa(i))
+		}
+	}
+	return templateURLs
+}
+
+// ValidateTemplate checks that template is valid JSON once its placeholders are replaced
+func ValidateTemplate(template string) error {
+	if !json.Valid([]byte(renderTemplate(template, "word", "example.com", "/word"))) {
+		return fmt.Errorf("invalid JSON: %s", template)
+	}
+	return nil
+}
+
+// AddTemplates sends the body templates as JSON POST requests for the URLs of TemplateURLs
+func (o *Overrides) AddTemplates(templates []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.templates = append(o.templates, templates...)
+}
+
+// templateSpec returns the POST request of the template selected by the fragment of parsed
+func (o *Overrides) templateSpec(parsed *url.URL) (Spec, bool) {
+	index, err := strconv.Atoi(strings.TrimPrefix(parsed.Fragment, templateFragment))
+	if err != nil || !strings.HasPrefix(parsed.Fragment, templateFragment) || index < 1 || index > len(o.templates) {
+		return Spec{}, false
+	}
+
+	body := renderTemplate(o.templates[index-1], path.Base(parsed.Path), parsed.Host, parsed.Path)
+	return Spec{
+		Method:  "POST",
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    []byte(body),
+	}, true
+}
+
+// renderTemplate replaces the placeholders {{word}} (last path segment), {{host}} and {{path}}
+// with JSON string escaped values, the placeholders are expected inside JSON strings
+func renderTemplate(template, word, host, path string) string {
+	return strings.NewReplacer(
+		"{{word}}", jsonEscape(word),
+		"{{host}}", jsonEscape(host),
+		"{{path}}", jsonEscape(path),
+	).Replace(template)
+}
+
+func jsonEscape(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted[1 : len(quoted)-1])
+}
@@ -0,0 +1,65 @@
+package result
+
+type Verdict int
+
+const (
+	// VerdictNone leaves the decision to the markers and rules
+	VerdictNone Verdict = iota
+	// VerdictMatch reports the response even if no marker or rule matched
+	VerdictMatch
+	// VerdictReject never reports the response
+	VerdictReject
+)
+
+// Analysis is the outcome of an Analyzer for a single response. Annotations are printed with the
+// finding, regardless of which check decided the match.
+type Analysis struct {
+	Verdict     Verdict
+	Detection   string
+	Annotations []string
+}
+
+// Analyzer extends the matching with custom detection logic. Analyzers run for every response
+// which passed the disallowed content type and string checks.
+type Analyzer interface {
+	Name() string
+	Analyze(res Result) Analysis
+}
+
+// Analyzers are the analyzers consulted by ProcessResult for the responses of one scan
+type Analyzers []Analyzer
+
+type analyzerOutcome struct {
+	analyzed    bool
+	verdict     Verdict
+	detection   string
+	annotations []string
+}
+
+// run combines the analyses of all analyzers, a rejection wins over a match
+func (analyzers Analyzers) run(res Result) analyzerOutcome {
+	outcome := analyzerOutcome{analyzed: len(analyzers) > 0}
+	for _, analyzer := range analyzers {
+		analysis := analyzer.Analyze(res)
+		for _, annotation := range analysis.Annotations {
+			outcome.annotations = append(outcome.annotations, analyzer.Name()+": "+annotation)
+		}
+
+		switch analysis.Verdict {
+		case VerdictReject:
+			outcome.verdict = VerdictReject
+		case VerdictMatch:
+			if outcome.verdict != VerdictReject {
+				outcome.verdict = VerdictMatch
+				if outcome.detection == "" {
+					outcome.detection = "analyzer:" + analyzer.Name()
+					if analysis.Detection != "" {
+						outcome.detection += ":" + analysis.Detection
+					}
+				}
+			}
+		}
+	}
+
+	return outcome
+}
@@ -0,0 +1,129 @@
+package result
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ResponseCache holds the results of recently requested URLs, so generated variants which result in
+// the same request (different host case, explicit default port) are only requested once.
+// Paths are not normalized, "a/./b" and "a/b" are different requests for the bypass payloads.
+type ResponseCache struct {
+	mu      sync.Mutex
+	results map[string]Result
+	// pending holds the requests in flight, workers asking for the same request wait for them
+	pending map[string]chan struct{}
+	// order holds the keys in insertion order, the oldest entry is evicted once the cache is full
+	order  []string
+	next   int
+	hits   int64
+	misses int64
+}
+
+// NewResponseCache returns a cache of size results, nil if size is zero
+func NewResponseCache(size int) *ResponseCache {
+	if size <= 0 {
+		return nil
+	}
+	return &ResponseCache{
+		results: make(map[string]Result, size),
+		pending: make(map[string]chan struct{}),
+		order:   make([]string, 0, size),
+	}
+}
+
+// Get returns the cached result of the request rawURL results in, with its URL set to rawURL. If the
+// request is in flight, Get waits for it. After a miss the caller has to Put the result of its
+// request, even a failed one.
+func (c *ResponseCache) Get(rawURL string) (Result, bool) {
+	if c == nil {
+		return Result{}, false
+	}
+	key := cacheKey(rawURL)
+
+	c.mu.Lock()
+	for {
+		if res, ok := c.results[key]; ok {
+			c.hits++
+			c.mu.Unlock()
+			res.URL = rawURL
+			return res, true
+		}
+		done, inFlight := c.pending[key]
+		if !inFlight {
+			break
+		}
+		c.mu.Unlock()
+		<-done
+		c.mu.Lock()
+		// A failed request is not cached, the next worker retries it
+	}
+	c.misses++
+	c.pending[key] = make(chan struct{})
+	c.mu.Unlock()
+	return Result{}, false
+}
+
+// Put stores res and releases the workers waiting for it. Failed requests are not cached as their
+// errors might be transient.
+func (c *ResponseCache) Put(res Result) {
+	if c == nil {
+		return
+	}
+	key := cacheKey(res.URL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if done, ok := c.pending[key]; ok {
+		delete(c.pending, key)
+		close(done)
+	}
+	if _, ok := c.results[key]; ok || res.Error != nil {
+		return
+	}
+	if len(c.order) < cap(c.order) {
+		c.order = append(c.order, key)
+	} else {
+		delete(c.results, c.order[c.next])
+		c.order[c.next] = key
+		c.next = (c.next + 1) % len(c.order)
+	}
+	c.results[key] = res
+}
+
+// Stats returns the number of requests answered from the cache and the number of requests sent
+func (c *ResponseCache) Stats() (hits, misses int64) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// cacheKey returns rawURL with lowercased scheme and host and without the default port. The
+// fragment is kept, it selects the body of -api-mode requests.
+func cacheKey(rawURL string) string {
+	schemeEnd := strings.Index(rawURL, "://")
+	if schemeEnd < 0 {
+		return rawURL
+	}
+	scheme := strings.ToLower(rawURL[:schemeEnd])
+	rest := rawURL[schemeEnd+3:]
+
+	hostEnd := strings.IndexAny(rest, "/?#")
+	if hostEnd < 0 {
+		hostEnd = len(rest)
+	}
+	host, path := strings.ToLower(rest[:hostEnd]), rest[hostEnd:]
+	if parsed, err := url.Parse(scheme + "://" + host); err == nil && parsed.Port() != "" {
+		if (scheme == "https" && parsed.Port() == "443") || (scheme == "http" && parsed.Port() == "80") {
+			host = strings.TrimSuffix(host, ":"+parsed.Port())
+		}
+	}
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
+	return scheme + "://" + host + path
+}
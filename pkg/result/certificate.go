@@ -0,0 +1,27 @@
+package result
+
+import (
+	"crypto/x509"
+	"time"
+)
+
+// Certificate is the metadata of the leaf certificate a host presented in the TLS handshake
+type Certificate struct {
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	SANs     []string  `json:"sans,omitempty"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+func NewCertificate(cert *x509.Certificate) *Certificate {
+	sans := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return &Certificate{
+		Subject:  cert.Subject.String(),
+		Issuer:   cert.Issuer.String(),
+		SANs:     sans,
+		NotAfter: cert.NotAfter,
+	}
+}
@@ -0,0 +1,59 @@
+package result
+
+import "strings"
+
+// Fingerprint normalizes the Server and X-Powered-By headers into a single technology string
+// like "microsoft-iis/10.0, asp.net". Product names are lowercased, comments like "(Ubuntu)" are
+// dropped and every product is listed once.
+func Fingerprint(server, poweredBy string) string {
+	var products []string
+	seen := make(map[string]bool)
+	add := func(product string) {
+		product = strings.ToLower(strings.Trim(product, " \t,;"))
+		if product == "" || seen[product] {
+			return
+		}
+		seen[product] = true
+		products = append(products, product)
+	}
+
+	// Server lists products separated by whitespace, optionally followed by a comment
+	for _, product := range strings.Fields(stripComments(server)) {
+		add(product)
+	}
+	// X-Powered-By is repeated or comma separated by most frameworks
+	for _, product := range strings.Split(stripComments(poweredBy), ",") {
+		add(product)
+	}
+
+	return strings.Join(products, ", ")
+}
+
+func stripComments(header string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range header {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// matchesServer reports whether fingerprint contains one of the case insensitive technologies
+func matchesServer(fingerprint string, technologies []string) bool {
+	if fingerprint == "" {
+		return false
+	}
+	for _, technology := range technologies {
+		if strings.Contains(fingerprint, strings.ToLower(technology)) {
+			return true
+		}
+	}
+	return false
+}
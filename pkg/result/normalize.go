@@ -0,0 +1,12 @@
+package result
+
+import "regexp"
+
+// NormalizeBody removes the parts of content matched by regexes, e.g. timestamps, CSRF tokens and
+// session IDs, so responses which only differ in them get the same hash
+func NormalizeBody(content string, regexes []*regexp.Regexp) string {
+	for _, re := range regexes {
+		content = re.ReplaceAllString(content, "")
+	}
+	return content
+}
@@ -0,0 +1,68 @@
+package result
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/fatih/color"
+	bolt "go.etcd.io/bbolt"
+)
+
+// responseBucket holds the hashes of the reported responses as keys without values
+var responseBucket = []byte("responses")
+
+// ResponseDB is a ResponseMap kept in a bbolt database on disk. Its memory usage does not grow
+// with the number of reported responses and it survives restarts, so a scan split over several
+// runs keeps suppressing the duplicates of the earlier runs. Every insert is a transaction which is
+// synced to disk before the finding is reported.
+type ResponseDB struct {
+	path string
+	db   *bolt.DB
+}
+
+// OpenResponseDB opens or creates the response database at path and uses it instead of the
+// in-memory ResponseMap for the duplicate checks
+func OpenResponseDB(path string) (*ResponseDB, error) {
+	// The timeout fails the open instead of waiting for another scan holding the file lock
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(responseBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	responseDB := &ResponseDB{path: path, db: db}
+	tracker = responseDB
+	return responseDB, nil
+}
+
+func (db *ResponseDB) insert(hash uint64) bool {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, hash)
+
+	inserted := false
+	err := db.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(responseBucket)
+		if bucket.Get(key) != nil {
+			return nil
+		}
+		inserted = true
+		return bucket.Put(key, []byte{})
+	})
+	if err != nil {
+		// Reporting a duplicate is better than losing a finding
+		color.Red("[✘] Error: Could not write to %s: %v", db.path, err)
+		return true
+	}
+	return inserted
+}
+
+func (db *ResponseDB) Close() error {
+	return db.db.Close()
+}
@@ -1,23 +1,82 @@
 package result
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
 	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/entropy"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/expr"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/filetype"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/jsonpath"
 	"github.com/fatih/color"
+	"hash/fnv"
+	"html"
 	"log"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Result struct {
-	URL         string
-	Content     string
-	Error       error
+	URL                 string
+	Content             string
+	Error               error
+	StatusCode          int
+	FileSize            int64
+	ContentType         string
+	DiffersFromBaseline bool
+	ContentHash         string
+	Title               string
+	FileType            string
+	Duration            time.Duration
+	SoftNotFound        bool
+	// FaviconHash is the mmh3 hash of the favicon of the host, empty if unknown
+	FaviconHash string
+	// Server is the technology fingerprint of the Server and X-Powered-By headers
+	Server string
+	// Blocked is set for block and challenge pages of a WAF, WAF names the WAF shielding the host
+	Blocked bool
+	WAF     string
+	// Certificate is the TLS certificate of the host, nil for plain HTTP or without -tls-info
+	Certificate *Certificate
+	// Tags are the tags of the host from the input
+	Tags []string
+	// DedupHeader is the value of the response header of -dedup-by header:<name>
+	DedupHeader string
+	// TransferSize is the number of bytes received for the response: status line, headers and the
+	// body bytes read
+	TransferSize int64
+	// ETag and LastModified are the validators of the response. Unchanged is set for the 304 answer
+	// to a conditional request of a URL known from -previous-store.
+	ETag         string
+	LastModified string
+	Unchanged    bool
+	// Unverified is set by the workers for a match of -verify which did not reproduce
+	Unverified bool
+}
+
+// Finding describes a reported match and which marker (or the rules) caused it
+type Finding struct {
+	URL       string
+	Detection string
+	// Marker is the matched marker, empty if the rules or an analyzer decided the match
+	Marker      string
 	StatusCode  int
 	FileSize    int64
 	ContentType string
+	// SHA256 is the hash of the body, of the normalized one with -normalize-regex
+	SHA256      string
+	Title       string
+	FaviconHash string
+	Server      string
+	WAF         string
+	Certificate *Certificate
+	Tags        []string
 }
 
 type ResponseMap struct {
@@ -50,8 +109,34 @@ func computeHash(host string, size int64) uint64 {
 	return (h & 0xFFFFFFFFFFFF) | (uint64(size&0xFFFF) << 48)
 }
 
-func (rm *ResponseMap) isNewResponse(host string, size int64) bool {
-	hash := computeHash(host, size)
+// responseTracker remembers the hashes of reported responses, insert reports whether hash is new
+type responseTracker interface {
+	insert(hash uint64) bool
+}
+
+func isNewResponse(host string, size int64) bool {
+	return tracker.insert(computeHash(host, size))
+}
+
+// isNewHeaderValue tracks responses by host and the value of the -dedup-by header
+func isNewHeaderValue(host, value string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(host))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return tracker.insert(h.Sum64())
+}
+
+// isNewContentHash tracks bodies by their SHA-256 regardless of host and size
+func isNewContentHash(contentHash string) bool {
+	raw, err := hex.DecodeString(contentHash)
+	if err != nil || len(raw) < 8 {
+		return true
+	}
+	return tracker.insert(binary.BigEndian.Uint64(raw[:8]))
+}
+
+func (rm *ResponseMap) insert(hash uint64) bool {
 	shard := rm.getShard(hash)
 
 	shard.Lock()
@@ -64,58 +149,282 @@ func (rm *ResponseMap) isNewResponse(host string, size int64) bool {
 	return true
 }
 
-func extractHost(urlStr string) string {
+// dedupHost returns the host the duplicate check of urlStr is tracked by. With crossProtocol all
+// schemes and ports of a host share it, so hosts mirroring their content on http and https are
+// reported once, otherwise every scheme and port is checked on its own.
+func dedupHost(urlStr string, crossProtocol bool) string {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
 		return urlStr // Return original if parsing fails
 	}
-	return parsedURL.Host
+	if crossProtocol {
+		return parsedURL.Hostname()
+	}
+	return parsedURL.Scheme + "://" + parsedURL.Host
+}
+
+// tracker is replaced by OpenResponseDB to persist the duplicate checks
+var tracker responseTracker = NewResponseMap()
+
+// ResetDuplicates forgets the responses of the in-memory duplicate check, so the next iteration of
+// -monitor reports its findings again. -monitor does not allow a -dedup-db.
+func ResetDuplicates() {
+	if _, ok := tracker.(*ResponseMap); ok {
+		tracker = NewResponseMap()
+	}
 }
 
-var tracker = NewResponseMap()
+var titleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
 
-func ProcessResult(result Result, cfg config.Config, markers []string) {
+func extractTitle(content string) string {
+	match := titleRegex.FindStringSubmatch(content)
+	if len(match) < 2 {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(match[1])), " ")
+}
+
+func computeContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ProcessResult prints result if it matches the configured markers and rules and reports whether it did
+func ProcessResult(result Result, cfg config.Config, markers []string, analyzers Analyzers) (Finding, bool) {
 	if result.Error != nil {
 		if cfg.Verbose {
 			log.Printf("Error processing %s: %v\n", result.URL, result.Error)
 		}
-		return
+		return Finding{}, false
+	}
+
+	if result.SoftNotFound {
+		if cfg.Verbose {
+			log.Printf("Skipped soft-404: %s (Status: %d, Size: %d bytes)\n", result.URL, result.StatusCode, result.FileSize)
+		}
+		return Finding{}, false
+	}
+
+	if result.Blocked {
+		if cfg.Verbose {
+			log.Printf("Skipped WAF block page: %s (Status: %d)\n", result.URL, result.StatusCode)
+		}
+		return Finding{}, false
+	}
+
+	eval, matched := evaluate(&result, cfg, markers, analyzers)
+	if !matched {
+		return Finding{}, false
+	}
+	match, markerFound, analysis := eval.match, eval.markerFound, eval.analysis
+
+	if result.Unverified {
+		if cfg.Verbose {
+			log.Printf("Skipped match which did not reproduce: %s\n", result.URL)
+		}
+		return Finding{}, false
+	}
+
+	host := dedupHost(result.URL, cfg.CrossProtocolDedup)
+	if !cfg.DisableDuplicateCheck {
+		if cfg.DedupBy == "hash" {
+			if !isNewContentHash(result.ContentHash) {
+				if cfg.Verbose {
+					log.Printf("Skipped duplicate response hash %s for %s\n", result.ContentHash, result.URL)
+				}
+				return Finding{}, false
+			}
+		} else if cfg.DedupBy == "header" && result.DedupHeader != "" {
+			if !isNewHeaderValue(host, result.DedupHeader) {
+				if cfg.Verbose {
+					log.Printf("Skipped duplicate %s %s for host %s\n", cfg.DedupHeader, result.DedupHeader, host)
+				}
+				return Finding{}, false
+			}
+		} else if !isNewResponse(host, eval.dedupSize) {
+			if cfg.Verbose {
+				log.Printf("Skipped duplicate response size %d for host %s\n", eval.dedupSize, host)
+			}
+			return Finding{}, false
+		}
+	}
+
+	if printedBefore(result.URL) {
+		if cfg.Verbose {
+			log.Printf("Skipped finding printed before: %s\n", result.URL)
+		}
+		return Finding{}, false
+	}
+
+	// If we get here, all configured conditions were met
+	if outputTemplate == nil {
+		printReport(result, eval, cfg)
+	}
+
+	if cfg.Verbose {
+		log.Printf("Processed: %s (Status: %d, Size: %d bytes, Type: %s)\n",
+			result.URL, result.StatusCode, result.FileSize, result.ContentType)
+	}
+
+	finding := Finding{
+		URL:         result.URL,
+		Detection:   "rules",
+		StatusCode:  result.StatusCode,
+		FileSize:    result.FileSize,
+		ContentType: result.ContentType,
+		SHA256:      result.ContentHash,
+		Title:       result.Title,
+		FaviconHash: result.FaviconHash,
+		Server:      result.Server,
+		WAF:         result.WAF,
+		Certificate: result.Certificate,
+		Tags:        result.Tags,
+	}
+	if markerFound {
+		finding.Detection = match.marker
+		finding.Marker = match.marker
+	} else if analysis.verdict == VerdictMatch {
+		finding.Detection = analysis.detection
+	}
+
+	if outputTemplate != nil {
+		printTemplate(result, finding)
+	}
+	return finding, true
+}
+
+// printReport prints the colored report of a finding with the checks it passed and a body excerpt
+func printReport(result Result, eval evaluation, cfg config.Config) {
+	match, markerFound, hasMarkers, analysis := eval.match, eval.markerFound, eval.hasMarkers, eval.analysis
+
+	color.Red("\n[!]\tMatch found in %s", result.URL)
+	if hasMarkers && markerFound {
+		color.Red("\tMarkers check: passed (%s)", match.marker)
+	}
+	if analysis.verdict == VerdictMatch {
+		color.Red("\tAnalyzer check: passed (%s)", analysis.detection)
+	}
+	for _, annotation := range analysis.annotations {
+		color.Red("\tAnnotation: %s", annotation)
+	}
+
+	color.Red("\tRules check: passed (S: %d, FS: %d, CT: %s)",
+		result.StatusCode, result.FileSize, result.ContentType)
+	color.Red("\tSHA-256: %s", result.ContentHash)
+	if result.Title != "" {
+		color.Red("\tTitle: %s", result.Title)
+	}
+	if result.FileType != "" {
+		color.Red("\tDetected file type: %s", result.FileType)
+	}
+	if result.FaviconHash != "" {
+		color.Red("\tFavicon hash: %s", result.FaviconHash)
+	}
+	if result.Server != "" {
+		color.Red("\tServer: %s", result.Server)
+	}
+	if result.WAF != "" {
+		color.Red("\tShielded by: %s", result.WAF)
+	}
+	if len(result.Tags) > 0 {
+		color.Red("\tTags: %s", strings.Join(result.Tags, ", "))
+	}
+	if cert := result.Certificate; cert != nil {
+		color.Red("\tCertificate: %s (Issuer: %s, Expires: %s)", cert.Subject, cert.Issuer, cert.NotAfter.Format("2006-01-02"))
+		if len(cert.SANs) > 0 {
+			color.Red("\tSANs: %s", strings.Join(cert.SANs, ", "))
+		}
 	}
 
+	var content string
+	if markerFound {
+		content = contextSnippet(result.Content, match.start, match.end, cfg.ContextBytes)
+	} else {
+		content = contextSnippet(result.Content, 0, 0, 2*cfg.ContextBytes)
+	}
+	content = strings.ReplaceAll(content, "\n", "")
+
+	color.Green("\n[!]\tBody: %s\n", content)
+}
+
+// evaluation is the outcome of matching a response against the markers, rules and analyzers
+type evaluation struct {
+	match       markerMatch
+	markerFound bool
+	hasMarkers  bool
+	analysis    analyzerOutcome
+	// dedupSize is the size of the body without the parts removed by the normalization
+	dedupSize int64
+}
+
+// evaluate derives hash, title and file type of result and reports whether it matches the markers
+// and rules
+func evaluate(result *Result, cfg config.Config, markers []string, analyzers Analyzers) (evaluation, bool) {
+	// The duplicate check compares the normalized body, the markers still see the original
+	dedupSize := result.FileSize
+	if len(cfg.NormalizeRegexes) > 0 {
+		normalized := NormalizeBody(result.Content, cfg.NormalizeRegexes)
+		dedupSize -= int64(len(result.Content) - len(normalized))
+		result.ContentHash = computeContentHash(normalized)
+	} else {
+		result.ContentHash = computeContentHash(result.Content)
+	}
+	if strings.Contains(strings.ToLower(result.ContentType), "html") || result.ContentType == "" {
+		result.Title = extractTitle(result.Content)
+	}
+	result.FileType = filetype.Detect(result.Content)
+
 	// Check if content type is disallowed first
 	DisallowedContentTypes := strings.ToLower(cfg.DisallowedContentTypes)
 	DisallowedContentTypesList := strings.Split(DisallowedContentTypes, ",")
 	if isDisallowedContentType(result.ContentType, DisallowedContentTypesList) {
-		return
+		return evaluation{}, false
 	}
 
 	// Check if content contains disallowed strings
 	DisallowedContentStrings := strings.ToLower(cfg.DisallowedContentStrings)
 	DisallowedContentStringsList := strings.Split(DisallowedContentStrings, ",")
 	if containsDisallowedStringInContent(result.Content, DisallowedContentStringsList) {
-		return
+		return evaluation{}, false
+	}
+
+	// Check if the host runs a technology whose favicon is filtered
+	if result.FaviconHash != "" && containsString(cfg.FilterFaviconHashes, result.FaviconHash) {
+		return evaluation{}, false
+	}
+
+	// Check if the host runs a filtered server technology
+	if len(cfg.FilterServers) > 0 && matchesServer(result.Server, cfg.FilterServers) {
+		return evaluation{}, false
+	}
+
+	analysis := analyzers.run(*result)
+	if analysis.verdict == VerdictReject {
+		if cfg.Verbose {
+			log.Printf("Rejected by analyzer: %s\n", result.URL)
+		}
+		return evaluation{}, false
 	}
 
 	markerFound := false
-	hasMarkers := len(markers) > 0
-	usedMarker := ""
+	hasMarkers := len(markers) > 0 || cfg.DetectSecrets
+	var match markerMatch
 
 	if hasMarkers {
-		for _, marker := range markers {
-			if strings.HasPrefix(marker, "regex:") == false && strings.Contains(result.Content, marker) {
-				markerFound = true
-				usedMarker = marker
-				break
-			}
+		markerContent := result.Content
+		if cfg.MarkersIgnoreCase {
+			// Markers were lowercased once by PrepareMarkers, so a single pass over the content is enough
+			markerContent = strings.ToLower(markerContent)
+		}
+		match, markerFound = findMarker(markerContent, markers, result.StatusCode, result.ContentType)
+	}
 
-			if strings.HasPrefix(marker, "regex:") {
-				regex := strings.TrimPrefix(marker, "regex:")
-				if match, _ := regexp.MatchString(regex, result.Content); match {
-					markerFound = true
-					usedMarker = marker
-					break
-				}
-			}
+	// High-entropy tokens count as a marker hit even when no explicit marker matched
+	if !markerFound && cfg.DetectSecrets {
+		if secrets := entropy.FindSecrets(result.Content, cfg.EntropyThreshold, cfg.EntropyMinLength); len(secrets) > 0 {
+			markerFound = true
+			start := strings.Index(result.Content, secrets[0])
+			match = markerMatch{marker: "entropy:" + secrets[0], start: start, end: start + len(secrets[0])}
 		}
 	}
 
@@ -134,19 +443,32 @@ func ProcessResult(result Result, cfg config.Config, markers []string) {
 		rulesCount++
 	}
 
-	if cfg.HTTPStatusCodes != "" {
-		AllowedHttpStatusesList := strings.Split(cfg.HTTPStatusCodes, ",")
-		for _, AllowedHttpStatusString := range AllowedHttpStatusesList {
-			allowedStatus, err := strconv.Atoi(strings.TrimSpace(AllowedHttpStatusString))
-			if err != nil {
-				log.Printf("Error converting status code '%s' to integer: %v", AllowedHttpStatusString, err)
-				continue
-			}
-			if result.StatusCode == allowedStatus {
-				rulesMatched++
-				break
-			}
-		}
+	if cfg.BaselineDiff {
+		rulesCount++
+	}
+
+	if cfg.TitleRegex != nil {
+		rulesCount++
+	}
+
+	if cfg.MatchExpr != nil {
+		rulesCount++
+	}
+
+	if cfg.DetectTypes != "" {
+		rulesCount++
+	}
+
+	if len(cfg.MatchFaviconHashes) > 0 {
+		rulesCount++
+	}
+
+	if len(cfg.MatchServers) > 0 {
+		rulesCount++
+	}
+
+	if cfg.HTTPStatusCodes != "" && cfg.StatusMatcher != nil && cfg.StatusMatcher.Matches(result.StatusCode) {
+		rulesMatched++
 	}
 
 	// Check content size
@@ -167,51 +489,293 @@ func ProcessResult(result Result, cfg config.Config, markers []string) {
 		}
 	}
 
+	// Check difference to the host root
+	if cfg.BaselineDiff && result.DiffersFromBaseline {
+		rulesMatched++
+	}
+
+	// Check page title
+	if cfg.TitleRegex != nil && result.Title != "" && cfg.TitleRegex.MatchString(result.Title) {
+		rulesMatched++
+	}
+
+	// Check the match expression
+	if cfg.MatchExpr != nil && cfg.MatchExpr.Match(expressionEnv(*result)) {
+		rulesMatched++
+	}
+
+	// Check detected file type (magic bytes)
+	if cfg.DetectTypes != "" && result.FileType != "" {
+		DetectTypesList := strings.Split(strings.ToLower(cfg.DetectTypes), ",")
+		for _, DetectTypeString := range DetectTypesList {
+			if strings.TrimSpace(DetectTypeString) == result.FileType {
+				rulesMatched++
+				break
+			}
+		}
+	}
+
+	// Check favicon hash of the host
+	if len(cfg.MatchFaviconHashes) > 0 && containsString(cfg.MatchFaviconHashes, result.FaviconHash) {
+		rulesMatched++
+	}
+
+	// Check server technology
+	if len(cfg.MatchServers) > 0 && matchesServer(result.Server, cfg.MatchServers) {
+		rulesMatched++
+	}
+
 	// Determine if rules match
 	rulesPass := rulesCount == 0 || (rulesCount > 0 && rulesMatched == rulesCount)
 
 	// Final decision based on both markers and rules
-	if (hasMarkers && !markerFound) || (rulesCount > 0 && !rulesPass) {
+	// Without markers and rules the analyzers alone decide
+	analyzersOnly := analysis.analyzed && !hasMarkers && rulesCount == 0
+
+	if analysis.verdict != VerdictMatch && ((hasMarkers && !markerFound) || (rulesCount > 0 && !rulesPass) || analyzersOnly) {
 		// If we have markers but didn't find one, OR if we have rules but they didn't pass, skip
 		if cfg.Verbose {
 			log.Printf("Skipped: %s (Status: %d, Size: %d bytes, Type: %s)\n",
 				result.URL, result.StatusCode, result.FileSize, result.ContentType)
 		}
-		return
+		return evaluation{}, false
 	}
 
-	host := extractHost(result.URL)
-	if !cfg.DisableDuplicateCheck {
-		if !tracker.isNewResponse(host, result.FileSize) {
-			if cfg.Verbose {
-				log.Printf("Skipped duplicate response size %d for host %s\n", result.FileSize, host)
+	return evaluation{match: match, markerFound: markerFound, hasMarkers: hasMarkers, analysis: analysis, dedupSize: dedupSize}, true
+}
+
+type markerMatch struct {
+	marker string
+	start  int
+	end    int
+}
+
+func findMarker(content string, markers []string, statusCode int, contentType string) (markerMatch, bool) {
+	for _, marker := range markers {
+		pattern := marker
+		if conditions, conditionalPattern, ok := splitConditionalMarker(marker); ok {
+			if !conditionsMatch(conditions, statusCode, contentType) {
+				continue
 			}
-			return
+			pattern = conditionalPattern
+		}
+
+		if loc := locateMarker(content, pattern); loc != nil {
+			return markerMatch{marker: marker, start: loc[0], end: loc[1]}, true
 		}
 	}
 
-	// If we get here, all configured conditions were met
-	color.Red("\n[!]\tMatch found in %s", result.URL)
-	if hasMarkers {
-		color.Red("\tMarkers check: passed (%s)", usedMarker)
+	return markerMatch{}, false
+}
+
+// contextSnippet returns the match plus up to contextBytes before and after it
+func contextSnippet(content string, start, end, contextBytes int) string {
+	if start < 0 || end > len(content) || start > end {
+		start, end = 0, 0
 	}
 
-	color.Red("\tRules check: passed (S: %d, FS: %d, CT: %s)",
-		result.StatusCode, result.FileSize, result.ContentType)
+	from := start - contextBytes
+	if from < 0 {
+		from = 0
+	}
 
-	content := result.Content
-	content = strings.ReplaceAll(content, "\n", "")
+	to := end + contextBytes
+	if to > len(content) {
+		to = len(content)
+	}
 
-	if len(content) > 150 {
-		color.Green("\n[!]\tBody: %s\n", content[:150])
-	} else {
-		color.Green("\n[!]\tBody: %s\n", content)
+	return content[from:to]
+}
+
+// locateMarker returns the position of the first occurrence of marker in content or nil
+func locateMarker(content, marker string) []int {
+	if strings.HasPrefix(marker, "jsonpath:") {
+		return locateJSONPath(content, strings.TrimPrefix(marker, "jsonpath:"))
 	}
 
-	if cfg.Verbose {
-		log.Printf("Processed: %s (Status: %d, Size: %d bytes, Type: %s)\n",
-			result.URL, result.StatusCode, result.FileSize, result.ContentType)
+	if strings.HasPrefix(marker, "regex:") {
+		regex, err := regexp.Compile(strings.TrimPrefix(marker, "regex:"))
+		if err != nil {
+			return nil
+		}
+		return regex.FindStringIndex(content)
+	}
+
+	if idx := strings.Index(content, marker); idx >= 0 {
+		return []int{idx, idx + len(marker)}
 	}
+
+	return nil
+}
+
+var jsonPaths sync.Map
+
+func locateJSONPath(content, expression string) []int {
+	trimmed := strings.TrimSpace(content)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil
+	}
+
+	compiled, ok := jsonPaths.Load(expression)
+	if !ok {
+		path, err := jsonpath.Compile(expression)
+		if err != nil {
+			log.Printf("Invalid jsonpath marker '%s': %v", expression, err)
+			path = nil
+		}
+		compiled, _ = jsonPaths.LoadOrStore(expression, path)
+	}
+
+	path := compiled.(*jsonpath.Path)
+	if path == nil {
+		return nil
+	}
+
+	values := path.Find(content)
+	if len(values) == 0 {
+		return nil
+	}
+
+	// Point the snippet at the first matched value if it can be found verbatim
+	if value, isString := values[0].(string); isString {
+		if idx := strings.Index(content, value); idx >= 0 {
+			return []int{idx, idx + len(value)}
+		}
+	}
+
+	return []int{0, 0}
+}
+
+// MarkerPattern returns the pattern of a marker without its status or content type conditions
+func MarkerPattern(marker string) string {
+	_, pattern, _ := splitConditionalMarker(marker)
+	return pattern
+}
+
+// splitConditionalMarker splits markers like ct=application/json;status=200:"accessKeyId"
+// into their conditions and the actual marker
+func splitConditionalMarker(marker string) (string, string, bool) {
+	if !strings.HasPrefix(marker, "ct=") && !strings.HasPrefix(marker, "status=") {
+		return "", marker, false
+	}
+
+	idx := strings.Index(marker, ":")
+	if idx < 0 {
+		return "", marker, false
+	}
+
+	pattern := marker[idx+1:]
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "\"") && strings.HasSuffix(pattern, "\"") {
+		pattern = pattern[1 : len(pattern)-1]
+	}
+
+	return marker[:idx], pattern, true
+}
+
+// conditionsMatch checks all ;-separated conditions, each condition may list several values separated by |
+func conditionsMatch(conditions string, statusCode int, contentType string) bool {
+	contentType = strings.ToLower(contentType)
+
+	for _, condition := range strings.Split(conditions, ";") {
+		parts := strings.SplitN(condition, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		matched := false
+		for _, value := range strings.Split(parts[1], "|") {
+			value = strings.TrimSpace(value)
+			switch strings.TrimSpace(parts[0]) {
+			case "ct":
+				matched = strings.Contains(contentType, strings.ToLower(value))
+			case "status":
+				matched = strconv.Itoa(statusCode) == value
+			default:
+				matched = true
+			}
+			if matched {
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateMarker checks the syntax of a single marker line
+func ValidateMarker(marker string) error {
+	if marker == "" {
+		return fmt.Errorf("empty marker matches every response")
+	}
+
+	pattern := marker
+	if conditions, conditionalPattern, ok := splitConditionalMarker(marker); ok {
+		for _, condition := range strings.Split(conditions, ";") {
+			parts := strings.SplitN(condition, "=", 2)
+			if len(parts) != 2 || (parts[0] != "ct" && parts[0] != "status") {
+				return fmt.Errorf("invalid condition '%s', expected ct=... or status=...", condition)
+			}
+			if parts[0] == "status" {
+				for _, value := range strings.Split(parts[1], "|") {
+					if _, err := strconv.Atoi(strings.TrimSpace(value)); err != nil {
+						return fmt.Errorf("invalid status code '%s' in condition", value)
+					}
+				}
+			}
+		}
+		pattern = conditionalPattern
+	} else if strings.HasPrefix(marker, "ct=") || strings.HasPrefix(marker, "status=") {
+		return fmt.Errorf("conditional marker without ':' separating conditions and marker")
+	}
+
+	switch {
+	case pattern == "":
+		return fmt.Errorf("empty marker matches every response")
+	case strings.HasPrefix(pattern, "regex:"):
+		if _, err := regexp.Compile(strings.TrimPrefix(pattern, "regex:")); err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+	case strings.HasPrefix(pattern, "jsonpath:"):
+		if _, err := jsonpath.Compile(strings.TrimPrefix(pattern, "jsonpath:")); err != nil {
+			return fmt.Errorf("invalid jsonpath: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// PrepareMarkers converts markers once before scanning according to the marker related flags
+func PrepareMarkers(markers []string, cfg config.Config) []string {
+	if !cfg.MarkersIgnoreCase {
+		return markers
+	}
+
+	prepared := make([]string, 0, len(markers))
+	for _, marker := range markers {
+		if conditions, pattern, ok := splitConditionalMarker(marker); ok {
+			prepared = append(prepared, conditions+":"+ignoreCaseMarker(pattern))
+			continue
+		}
+		prepared = append(prepared, ignoreCaseMarker(marker))
+	}
+	return prepared
+}
+
+func ignoreCaseMarker(marker string) string {
+	if strings.HasPrefix(marker, "jsonpath:") {
+		// The body is lowercased as well, so keys have to be lowercased too
+		return "jsonpath:" + strings.ToLower(strings.TrimPrefix(marker, "jsonpath:"))
+	}
+
+	if strings.HasPrefix(marker, "regex:") {
+		// Regexes keep their case (character classes), but match case-insensitively
+		return "regex:(?i)" + strings.TrimPrefix(marker, "regex:")
+	}
+	return strings.ToLower(marker)
 }
 
 func containsDisallowedStringInContent(contentBody string, DisallowedContentStringsList []string) bool {
@@ -232,6 +796,34 @@ func containsDisallowedStringInContent(contentBody string, DisallowedContentStri
 	return false
 }
 
+// expressionEnv returns the variables of result for -match-expr
+func expressionEnv(result Result) expr.Env {
+	env := expr.Env{
+		Status:      result.StatusCode,
+		Size:        result.FileSize,
+		DurationMs:  result.Duration.Milliseconds(),
+		ContentType: strings.ToLower(result.ContentType),
+		Body:        result.Content,
+		Title:       result.Title,
+		Server:      result.Server,
+		FileType:    result.FileType,
+		URL:         result.URL,
+	}
+	if parsed, err := url.Parse(result.URL); err == nil {
+		env.Path = parsed.Path
+	}
+	return env
+}
+
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
 func isDisallowedContentType(contentType string, DisallowedContentTypesList []string) bool {
 
 	if len(DisallowedContentTypesList) == 0 {
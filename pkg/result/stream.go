@@ -0,0 +1,179 @@
+package result
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/ahocorasick"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
+)
+
+// BodyScanner searches the plain-text markers and disallowed strings while the HTTP clients read a
+// response body, so the download can stop as soon as the outcome is decided instead of always
+// reading MaxContentRead bytes.
+type BodyScanner struct {
+	markers      *ahocorasick.Automaton
+	disallowed   *ahocorasick.Automaton
+	contextBytes int
+	// chunks holds the buffers of -read-buffer bytes the bodies are read in
+	chunks sync.Pool
+}
+
+// NewBodyScanner returns nil if stopping early could change the result, e.g. because regex,
+// jsonpath or conditional markers, -match-expr, -filter-title-regex, secret detection, marker
+// reloading, body hashes for the duplicate check or soft-404 calibration, the baseline diff or
+// analyzers need the full body.
+func NewBodyScanner(markers []string, cfg config.Config, analyzers Analyzers) *BodyScanner {
+	if cfg.DetectSecrets || cfg.MarkersReloadInterval > 0 || cfg.DedupBy == "hash" || cfg.NormalizeBody ||
+		cfg.Calibrate || cfg.BaselineDiff || cfg.MatchExpr != nil || cfg.TitleRegex != nil ||
+		len(analyzers) > 0 {
+		return nil
+	}
+
+	for _, marker := range markers {
+		if _, _, conditional := splitConditionalMarker(marker); conditional {
+			return nil
+		}
+		if strings.HasPrefix(marker, "regex:") || strings.HasPrefix(marker, "jsonpath:") {
+			return nil
+		}
+	}
+
+	var disallowed []string
+	for _, s := range strings.Split(strings.ToLower(cfg.DisallowedContentStrings), ",") {
+		if s != "" {
+			disallowed = append(disallowed, s)
+		}
+	}
+
+	scanner := &BodyScanner{contextBytes: cfg.ContextBytes}
+	scanner.chunks.New = func() interface{} {
+		chunk := make([]byte, cfg.ReadBuffer)
+		return &chunk
+	}
+	// A disallowed string after the marker would still reject the response, so the body is
+	// only cut after a marker if there are none
+	if len(markers) > 0 && len(disallowed) == 0 {
+		// Markers were already lowercased by PrepareMarkers
+		scanner.markers = ahocorasick.Build(markers, cfg.MarkersIgnoreCase)
+	}
+	if len(disallowed) > 0 {
+		scanner.disallowed = ahocorasick.Build(disallowed, false)
+	}
+
+	if scanner.markers == nil && scanner.disallowed == nil {
+		return nil
+	}
+	return scanner
+}
+
+// BodySession holds the matching state for a single response body
+type BodySession struct {
+	scanner         *BodyScanner
+	markerState     int32
+	disallowedState int32
+	read            int
+	stopAt          int
+}
+
+// NewSession returns the session of a response body. The size of a response without
+// Content-Length or Content-Range is the length of its body, sizeKnown false returns nil so such
+// a body is read in full and its size is not cut short for the duplicate check and
+// -min-content-size.
+func (s *BodyScanner) NewSession(sizeKnown bool) *BodySession {
+	if s == nil || !sizeKnown {
+		return nil
+	}
+	return &BodySession{scanner: s, stopAt: -1}
+}
+
+// Write feeds the next chunk of the body and reports whether the rest of the body can be skipped.
+// After a marker the context bytes printed with the finding are still read.
+func (s *BodySession) Write(chunk []byte) bool {
+	if s == nil {
+		return false
+	}
+
+	if s.scanner.disallowed != nil {
+		var match int
+		s.disallowedState, match, _ = s.scanner.disallowed.Scan(s.disallowedState, chunk)
+		if match >= 0 {
+			return true
+		}
+	}
+
+	if s.scanner.markers != nil && s.stopAt < 0 {
+		var match, end int
+		s.markerState, match, end = s.scanner.markers.Scan(s.markerState, chunk)
+		if match >= 0 {
+			s.stopAt = s.read + end + s.scanner.contextBytes
+		}
+	}
+
+	s.read += len(chunk)
+	return s.stopAt >= 0 && s.read >= s.stopAt
+}
+
+// ReadBody reads r into buffer. With a session it stops as soon as the session is decided.
+func ReadBody(buffer *bytes.Buffer, r io.Reader, session *BodySession) error {
+	if session == nil {
+		_, err := buffer.ReadFrom(r)
+		return err
+	}
+
+	pooled := session.scanner.chunks.Get().(*[]byte)
+	defer session.scanner.chunks.Put(pooled)
+	chunk := *pooled
+
+	for {
+		n, err := r.Read(chunk)
+		buffer.Write(chunk[:n])
+		if session.Write(chunk[:n]) {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ErrTarpit aborts bodies which are read slower than -min-transfer-rate
+var ErrTarpit = errors.New("tarpit, body read slower than the minimum transfer rate")
+
+// tarpitGrace is the time a body may take before its transfer rate is checked, so a slow start
+// of a regular response is not mistaken for a tarpit
+const tarpitGrace = 2 * time.Second
+
+type rateReader struct {
+	r       io.Reader
+	minRate int64
+	start   time.Time
+	read    int64
+}
+
+// LimitRate returns r, which fails with ErrTarpit once it is read slower than minRate bytes per
+// second after tarpitGrace. With a minRate of zero r is returned unchanged.
+func LimitRate(r io.Reader, minRate int64) io.Reader {
+	if minRate <= 0 {
+		return r
+	}
+	return &rateReader{r: r, minRate: minRate, start: time.Now()}
+}
+
+func (r *rateReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if err == nil {
+		if elapsed := time.Since(r.start); elapsed > tarpitGrace && float64(r.read) < float64(r.minRate)*elapsed.Seconds() {
+			return n, ErrTarpit
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,83 @@
+package result
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// outputTemplate renders the findings instead of the colored report, set by SetOutputFormat
+var outputTemplate *template.Template
+
+// templateFinding holds the fields of a finding available to -output-format
+type templateFinding struct {
+	URL         string
+	Host        string
+	Status      int
+	Size        int64
+	ContentType string
+	Detection   string
+	Marker      string
+	Title       string
+	SHA256      string
+	FileType    string
+	Server      string
+	WAF         string
+	FaviconHash string
+	DurationMs  int64
+	Tags        []string
+}
+
+// SetOutputFormat makes ProcessResult print every finding as a line rendered from the text/template
+// format to stdout instead of the colored report, e.g. '{{.Status}} {{.Size}} {{.URL}} {{.Marker}}'
+func SetOutputFormat(format string) error {
+	tmpl, err := template.New("output-format").Funcs(template.FuncMap{"join": strings.Join}).Parse(format)
+	if err != nil {
+		return err
+	}
+	// Unknown fields are only reported when the template is executed
+	if err := tmpl.Execute(io.Discard, templateFinding{}); err != nil {
+		return err
+	}
+	outputTemplate = tmpl
+	return nil
+}
+
+// printTemplate prints finding of res with the output template
+func printTemplate(res Result, finding Finding) {
+	data := templateFinding{
+		URL:         finding.URL,
+		Host:        hostname(finding.URL),
+		Status:      finding.StatusCode,
+		Size:        finding.FileSize,
+		ContentType: finding.ContentType,
+		Detection:   finding.Detection,
+		Marker:      finding.Marker,
+		Title:       res.Title,
+		SHA256:      res.ContentHash,
+		FileType:    res.FileType,
+		Server:      finding.Server,
+		WAF:         finding.WAF,
+		FaviconHash: finding.FaviconHash,
+		DurationMs:  res.Duration.Milliseconds(),
+		Tags:        finding.Tags,
+	}
+
+	var line strings.Builder
+	if err := outputTemplate.Execute(&line, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error rendering -output-format for %s: %v\n", finding.URL, err)
+		return
+	}
+	fmt.Fprintln(os.Stdout, strings.TrimRight(line.String(), "\n"))
+}
+
+func hostname(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
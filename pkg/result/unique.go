@@ -0,0 +1,24 @@
+package result
+
+import "github.com/dsecuredcom/dynamic-file-searcher/pkg/bloom"
+
+// uniqueFalsePositiveRate is the chance of a URL not being printed although it was not printed before
+const uniqueFalsePositiveRate = 0.000001
+
+// printedURLs holds the URLs of the printed findings with -unique
+var printedURLs *bloom.Filter
+
+// SetUnique makes ProcessResult print every URL at most once, even if it matches again through
+// another generation route. The set of printed URLs is a bloom filter sized for capacity URLs, so
+// its memory usage does not grow; beyond capacity new URLs are increasingly often taken for printed.
+func SetUnique(capacity uint64) {
+	printedURLs = bloom.New(capacity, uniqueFalsePositiveRate)
+}
+
+// printedBefore records rawURL as printed and reports whether it was printed before
+func printedBefore(rawURL string) bool {
+	if printedURLs == nil {
+		return false
+	}
+	return !printedURLs.AddIfNew(rawURL)
+}
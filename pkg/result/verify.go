@@ -0,0 +1,30 @@
+package result
+
+import (
+	"log"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
+)
+
+// Matches reports whether res matches the markers and rules, the duplicate checks left out. The
+// workers use it to pick the responses -verify requests a second time.
+func Matches(res Result, cfg config.Config, markers []string, analyzers Analyzers) bool {
+	_, matched := evaluate(&res, cfg, markers, analyzers)
+	return matched
+}
+
+// Reproduces reports whether again, the verification response of the matched res, still matches
+func Reproduces(res, again Result, cfg config.Config, markers []string, analyzers Analyzers) bool {
+	if again.Error != nil {
+		if cfg.Verbose {
+			log.Printf("Could not verify match %s: %v\n", res.URL, again.Error)
+		}
+		return false
+	}
+
+	// The host level details are not requested again
+	again.SoftNotFound = res.SoftNotFound
+	again.DiffersFromBaseline = res.DiffersFromBaseline
+	again.FaviconHash = res.FaviconHash
+	return Matches(again, cfg, markers, analyzers)
+}
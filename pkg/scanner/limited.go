@@ -0,0 +1,40 @@
+package scanner
+
+import (
+	"context"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/hosts"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/waf"
+	"golang.org/x/time/rate"
+)
+
+// limitedClient makes the requests of calibration, root diffing, favicons, JS discovery and
+// OpenAPI expansion wait for the same shields, rate groups and limiter as the scan, so the rate
+// limits of a host hold for all of its requests
+type limitedClient struct {
+	client     Client
+	ctx        context.Context
+	shields    *waf.Shields
+	rateGroups *hosts.RateGroups
+	limiter    *rate.Limiter
+}
+
+// NewLimitedClient returns client waiting for shields, rateGroups and limiter before every request
+// until ctx is cancelled. shields and rateGroups may be nil.
+func NewLimitedClient(ctx context.Context, client Client, shields *waf.Shields, rateGroups *hosts.RateGroups, limiter *rate.Limiter) Client {
+	return &limitedClient{client: client, ctx: ctx, shields: shields, rateGroups: rateGroups, limiter: limiter}
+}
+
+func (c *limitedClient) MakeRequest(url string) result.Result {
+	if err := c.shields.Wait(c.ctx, url); err != nil {
+		return result.Result{URL: url, Error: err}
+	}
+	if err := c.rateGroups.Wait(c.ctx, url); err != nil {
+		return result.Result{URL: url, Error: err}
+	}
+	if err := c.limiter.Wait(c.ctx); err != nil {
+		return result.Result{URL: url, Error: err}
+	}
+	return c.client.MakeRequest(url)
+}
@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/bloom"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
+	"github.com/fatih/color"
+)
+
+// resumeFilterCapacity sizes the URL filter for -resume-file if -url-dedup-capacity disabled it
+const resumeFilterCapacity = 10000000
+
+// resumeState records the processed URLs in the -resume-file. Started again with the same flags,
+// a scan stopped by -max-total-bytes, -max-runtime or an interrupt skips the recorded URLs. A nil
+// *resumeState records nothing.
+type resumeState struct {
+	file   *os.File
+	writer *bufio.Writer
+	// loaded is the number of URLs recorded by earlier runs
+	loaded int
+}
+
+// openResumeState adds the URLs recorded in filename to seen, so the generation skips them, and
+// appends the URLs processed from now on
+func openResumeState(filename string, seen *bloom.Filter) (*resumeState, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &resumeState{file: file}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			seen.AddIfNew(line)
+			r.loaded++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// The reads left the offset at the end of the file
+	r.writer = bufio.NewWriter(file)
+	return r, nil
+}
+
+// Record appends a processed URL
+func (r *resumeState) Record(url string) error {
+	if r == nil {
+		return nil
+	}
+	if _, err := r.writer.WriteString(url); err != nil {
+		return err
+	}
+	return r.writer.WriteByte('\n')
+}
+
+// Close writes the recorded URLs to disk, the state of a scan stopped by -max-runtime or
+// -max-total-bytes is complete once Run returned
+func (r *resumeState) Close() error {
+	if r == nil {
+		return nil
+	}
+	err := r.writer.Flush()
+	if err == nil {
+		err = r.file.Sync()
+	}
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// printResumeHint tells how a scan stopped by one of its limits is continued
+func printResumeHint(cfg config.Config) {
+	if cfg.ResumeFile != "" {
+		color.Yellow("[!] Run the same command again to continue with the URLs not recorded in %s", cfg.ResumeFile)
+	} else {
+		color.Yellow("[!] Use -resume-file to continue such a scan later")
+	}
+}
@@ -0,0 +1,909 @@
+// Package scanner contains the URL generation and scan engine, so it can be embedded into other
+// Go tools without shelling out to the binary:
+//
+//	cfg := config.ParseFlags() // or a Config populated like ParseFlags does
+//	err := scanner.New(cfg).Run(ctx, func(finding scanner.Finding) {
+//		fmt.Println(finding.URL, finding.Detection)
+//	})
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/baseline"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/bloom"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/checks"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/cloudstorage"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/control"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/distributed"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/domain"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/endpoints"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/favicon"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/hooks"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/hosts"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/markers"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/metrics"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/openapi"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/output"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/request"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/spill"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/utils"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/waf"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/wayback"
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"golang.org/x/time/rate"
+)
+
+const (
+	estimateTopDomains     = 20
+	pipelineReportInterval = 10 * time.Second
+)
+
+type Finding = result.Finding
+
+type Scanner struct {
+	cfg config.Config
+
+	// Progress prints a progress line every second
+	Progress bool
+	// Controls enables the keyboard controls if stdin is a terminal
+	Controls bool
+	// OnWildcardHost is called when a host is flagged by -wildcard-ratio, the findings reported for
+	// it so far are suspect
+	OnWildcardHost func(host string)
+	// OnUnchanged is called with the URLs answered with a 304 to the conditional requests of
+	// -previous-store or -monitor, they are not checked again
+	OnUnchanged func(url string)
+	// Known reports the URLs found by an earlier iteration of -monitor, -on-match-exec is not run for
+	// their findings again
+	Known func(url string) bool
+
+	aborted int32
+	// analyzers are consulted in addition to the ones of -cloud-storage and -checks
+	analyzers result.Analyzers
+}
+
+func New(cfg config.Config) *Scanner {
+	return &Scanner{cfg: cfg}
+}
+
+// AddAnalyzer adds an analyzer which is consulted for the responses of every following Run
+func (s *Scanner) AddAnalyzer(analyzer result.Analyzer) {
+	s.analyzers = append(s.analyzers, analyzer)
+}
+
+type input struct {
+	domains       []string
+	paths         []string
+	priorityPaths []string
+	markers       []string
+	streamDomains bool
+	consumeOnly   bool
+	exclude       *domain.ExcludeFilter
+	// cloud generates bucket URLs instead of paths in cloud storage mode
+	cloud *cloudstorage.Scanner
+	// checks validates the responses of the built-in check paths
+	checks *checks.Set
+	// tags are attached to the results of their hosts
+	tags *domain.Tags
+	// pathsMap replaces paths with tailored wordlists for the hosts it maps
+	pathsMap *domain.PathsMap
+	// dnsWildcards drops the hosts of wildcard DNS records from the domains of the phases
+	dnsWildcards *domain.DNSWildcards
+}
+
+func (s *Scanner) loadInput() (input, error) {
+	cfg := s.cfg
+	in := input{
+		streamDomains: cfg.DomainsFile == "-",
+		// Instances without own input only work off the shared Redis queue
+		consumeOnly: cfg.RedisURL != "" && !cfg.HasDomainInput(),
+		tags:        domain.NewTags(),
+	}
+	exclude, err := domain.NewExcludeFilter(cfg.ExcludeDomainsFile, cfg.ExcludeRegex)
+	if err != nil {
+		return input{}, fmt.Errorf("could not read -exclude-domains: %w", err)
+	}
+	in.exclude = exclude
+	if cfg.DNSWildcard {
+		in.dnsWildcards = domain.NewDNSWildcards(cfg.Verbose)
+	}
+	if cfg.DomainTagsFile != "" {
+		if err := in.tags.Load(cfg.DomainTagsFile); err != nil {
+			return input{}, fmt.Errorf("could not read -domain-tags: %w", err)
+		}
+	}
+	if cfg.PathsMapFile != "" {
+		pathsMap, err := domain.NewPathsMap(cfg.PathsMapFile)
+		if err != nil {
+			return input{}, fmt.Errorf("could not read paths map %s: %w", cfg.PathsMapFile, err)
+		}
+		in.pathsMap = pathsMap
+	}
+
+	if !in.streamDomains && !in.consumeOnly {
+		var domains []string
+		if cfg.DomainsFile != "" || cfg.Domain != "" {
+			if domains, err = domain.GetDomains(cfg.DomainsFile, cfg.Domain); err != nil {
+				return input{}, fmt.Errorf("could not read -domains: %w", err)
+			}
+		}
+		for i, d := range domains {
+			domains[i] = in.tags.Strip(d)
+		}
+		domains = append(domains, cfg.ImportedTargets...)
+		in.domains = in.exclude.Filter(domains)
+	}
+	if in.paths, err = readLines(cfg.PathsFiles); err != nil {
+		return input{}, fmt.Errorf("could not read -paths: %w", err)
+	}
+	in.paths = append(in.paths, cfg.Paths...)
+	in.paths = utils.UniqueStrings(in.paths)
+	if in.priorityPaths, err = readLines(cfg.PriorityPathsFiles); err != nil {
+		return input{}, fmt.Errorf("could not read -priority-paths: %w", err)
+	}
+	in.priorityPaths = utils.UniqueStrings(in.priorityPaths)
+	in.paths = withoutStrings(in.paths, in.priorityPaths)
+	if in.markers, err = readLines(cfg.MarkersFiles); err != nil {
+		return input{}, fmt.Errorf("could not read -markers: %w", err)
+	}
+	in.markers = append(in.markers, cfg.Markers...)
+	in.markers = result.PrepareMarkers(in.markers, cfg)
+
+	return in, nil
+}
+
+// readLines returns the lines of all files
+func readLines(files []string) ([]string, error) {
+	var lines []string
+	for _, file := range files {
+		fileLines, err := utils.ReadLines(file)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, fileLines...)
+	}
+	return lines, nil
+}
+
+// pathGroups returns the paths in the order they are requested on a host, priority paths first
+func (in input) pathGroups() [][]string {
+	if len(in.priorityPaths) == 0 {
+		return [][]string{in.paths}
+	}
+	return [][]string{in.priorityPaths, in.paths}
+}
+
+// phases splits the URL generation into phases which run one after another. With priority paths
+// and a known domain list the priority paths of all hosts are requested before any other path.
+// Streamed domains are only seen once, so their priority paths just go first per host.
+// hostPaths adds paths per host, e.g. from the Wayback Machine, after the configured ones.
+func (in input) phases(domainChan <-chan string, hostPaths func(string) []string, cfg config.Config) []urlPhase {
+	if in.cloud != nil {
+		return []urlPhase{{domains: domainChan, urls: in.bucketURLs(cfg)}}
+	}
+	if in.streamDomains || len(in.priorityPaths) == 0 {
+		return []urlPhase{{domains: in.dnsWildcards.Filter(domainChan), pathGroups: in.pathGroups(), pathsMap: in.pathsMap, hostPaths: hostPaths}}
+	}
+	return []urlPhase{
+		{domains: in.dnsWildcards.Filter(domainChan), pathGroups: [][]string{in.priorityPaths}},
+		{domains: in.dnsWildcards.Filter(domainQueue(in.domains)), pathGroups: [][]string{in.paths}, pathsMap: in.pathsMap, hostPaths: hostPaths},
+	}
+}
+
+// bucketURLs returns the cloud storage bucket URLs of a domain, based on the words of the path generation
+func (in input) bucketURLs(cfg config.Config) func(string) []string {
+	return func(d string) []string {
+		return in.cloud.URLs(d, domain.Words(d, &cfg))
+	}
+}
+
+// newCloudStorage returns the bucket URL generator for -cloud-storage or nil
+func newCloudStorage(cfg config.Config) (*cloudstorage.Scanner, error) {
+	if len(cfg.CloudStorage) == 0 {
+		return nil, nil
+	}
+	return cloudstorage.NewScanner(cfg.CloudStorage)
+}
+
+// addChecks requests the paths of the -checks sets before all other paths
+func (in *input) addChecks(cfg config.Config) error {
+	if len(cfg.Checks) == 0 {
+		return nil
+	}
+	set, err := checks.New(cfg.Checks)
+	if err != nil {
+		return err
+	}
+	in.checks = set
+	in.priorityPaths = utils.UniqueStrings(append(set.Paths(), in.priorityPaths...))
+	in.paths = withoutStrings(in.paths, in.priorityPaths)
+	return nil
+}
+
+// joinHostPaths returns a function returning the paths of all sources for a host, nil without sources
+func joinHostPaths(sources []func(string) []string) func(string) []string {
+	if len(sources) == 0 {
+		return nil
+	}
+	return func(host string) []string {
+		var paths []string
+		for _, source := range sources {
+			paths = append(paths, source(host)...)
+		}
+		return utils.UniqueStrings(paths)
+	}
+}
+
+// domainQueue returns a closed channel holding all domains
+func domainQueue(domains []string) <-chan string {
+	queue := make(chan string, len(domains))
+	for _, d := range domains {
+		queue <- d
+	}
+	close(queue)
+	return queue
+}
+
+func withoutStrings(slice, remove []string) []string {
+	if len(remove) == 0 {
+		return slice
+	}
+	removed := make(map[string]struct{}, len(remove))
+	for _, s := range remove {
+		removed[s] = struct{}{}
+	}
+	kept := slice[:0]
+	for _, s := range slice {
+		if _, exists := removed[s]; !exists {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// Run scans all configured domains and paths and calls onFinding for every finding. It returns
+// once all URLs were processed, ctx was cancelled or -max-findings/-max-runtime/-max-total-bytes
+// was reached.
+func (s *Scanner) Run(ctx context.Context, onFinding func(Finding)) error {
+	cfg := s.cfg
+	atomic.StoreInt32(&s.aborted, 0)
+	defer func(parent context.Context) {
+		if parent.Err() != nil {
+			atomic.StoreInt32(&s.aborted, 1)
+		}
+	}(ctx)
+	in, err := s.loadInput()
+	if err != nil {
+		return err
+	}
+
+	cloud, err := newCloudStorage(cfg)
+	if err != nil {
+		return err
+	}
+	if cloud != nil {
+		// The provider error signatures decide which buckets are reported
+		in.cloud = cloud
+	}
+	if err := in.addChecks(cfg); err != nil {
+		return err
+	}
+
+	var onMatchExec *hooks.Executor
+	if cfg.OnMatchExec != "" {
+		if onMatchExec, err = hooks.NewExecutor(cfg.OnMatchExec, cfg.OnMatchExecParallel, cfg.OnMatchExecTimeout, cfg.Verbose); err != nil {
+			return fmt.Errorf("invalid -on-match-exec: %w", err)
+		}
+	}
+	analyzers := append(result.Analyzers{}, s.analyzers...)
+	if in.cloud != nil {
+		analyzers = append(analyzers, in.cloud.Analyzer())
+	}
+	if in.checks != nil {
+		analyzers = append(analyzers, in.checks.Analyzer())
+	}
+
+	if !in.consumeOnly {
+		if err := validateInput(in, cfg.DetectSecrets); err != nil {
+			return err
+		}
+	}
+
+	if in.consumeOnly {
+		color.Cyan("[i] Scanning URLs from the Redis queue %s:urls", cfg.RedisPrefix)
+	} else {
+		printInitialInfo(cfg, in)
+	}
+
+	var storeAll *output.StoreAllWriter
+	if cfg.StoreAllFile != "" {
+		storeAll, err = output.NewStoreAllWriter(cfg.StoreAllFile)
+		if err != nil {
+			return fmt.Errorf("could not create %s: %w", cfg.StoreAllFile, err)
+		}
+	}
+
+	// Cancelling ctx stops URL generation and lets the workers finish gracefully
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var redisQueue *distributed.RedisQueue
+	if cfg.RedisURL != "" {
+		var err error
+		redisQueue, err = distributed.NewRedisQueue(cfg.RedisURL, cfg.RedisPrefix, cfg.LeaseTimeout, cfg.RedisIdleTimeout, cfg.Verbose)
+		if err != nil {
+			return fmt.Errorf("could not connect to Redis: %w", err)
+		}
+		defer redisQueue.Close()
+	}
+
+	limiter := rate.NewLimiter(rate.Limit(cfg.Concurrency), 1)
+
+	urlChan := make(chan string, cfg.URLBuffer)
+	resultsChan := make(chan result.Result, cfg.ResultBuffer)
+
+	var admission *control.Admission
+	if cfg.MemoryLimit > 0 && cfg.AdmissionThreshold > 0 {
+		// Throttle the URL generation before the heap reaches the memory limit
+		admission = control.NewAdmission(cfg.MemoryLimit, cfg.AdmissionThreshold, cfg.AdmissionShrinkReads, cfg.Verbose)
+		go admission.Run(ctx)
+	}
+
+	var overrides *request.Overrides
+	if in.checks != nil {
+		overrides = in.checks.Overrides()
+	}
+	if len(cfg.APITemplates) > 0 {
+		if overrides == nil {
+			overrides = request.NewOverrides()
+		}
+		overrides.AddTemplates(cfg.APITemplates)
+	}
+	client := NewClient(cfg, result.NewBodyScanner(in.markers, cfg, analyzers), admission, overrides)
+
+	if cfg.Unique {
+		result.SetUnique(cfg.UniqueCapacity)
+	}
+	if cfg.Resolver != nil {
+		domain.SetResolver(cfg.Resolver)
+	}
+
+	var shields *waf.Shields
+	if cfg.WAFDetect {
+		shields = waf.NewShields(cfg.WAFRate)
+	}
+
+	cache := result.NewResponseCache(cfg.ResponseCache)
+
+	var rateGroups *hosts.RateGroups
+	if cfg.RateGroupsFile != "" {
+		rateGroups, err = hosts.NewRateGroups(cfg.RateGroupsFile)
+		if err != nil {
+			return fmt.Errorf("could not read rate groups: %w", err)
+		}
+		color.Cyan("[i] Limiting the requests of %d host patterns", rateGroups.Len())
+	}
+
+	// The requests made besides the generated URLs are rate limited like them
+	limited := NewLimitedClient(ctx, client, shields, rateGroups, limiter)
+
+	var calibrator *baseline.Calibrator
+	if cfg.Calibrate {
+		calibrator = baseline.NewCalibrator(limited, cfg.CalibrationRequests)
+		calibrator.SetNormalization(cfg.NormalizeRegexes)
+	}
+
+	var rootDiffer *baseline.RootDiffer
+	if cfg.BaselineDiff {
+		rootDiffer = baseline.NewRootDiffer(limited, cfg.BaselineSimilarity)
+	}
+
+	var favicons *favicon.Fingerprinter
+	if cfg.Favicon {
+		favicons = favicon.NewFingerprinter(limited)
+	}
+
+	wildcards := hosts.NewWildcardDetector(cfg.WildcardRatio, cfg.WildcardMinRequests, cfg.MinContentSize)
+
+	var matchTracker *hosts.MatchTracker
+	if cfg.StopHostOnMatch {
+		matchTracker = hosts.NewMatchTracker(1, true)
+	} else if cfg.MaxMatchesPerHost > 0 {
+		matchTracker = hosts.NewMatchTracker(cfg.MaxMatchesPerHost, cfg.MaxMatchesSkipRequests)
+	}
+
+	var processedCount int64
+	var totalURLs int64
+	// generating is 1 until the URL generation finished and totalURLs is final
+	generating := int32(1)
+
+	// Snapshots of the progress for orchestration, on SIGUSR1 and the status socket
+	status := metrics.NewStatus(&processedCount, &totalURLs, &generating)
+	go status.Run(ctx)
+	if cfg.StatusSocket != "" {
+		go func() {
+			if err := status.Serve(ctx, cfg.StatusSocket); err != nil {
+				color.Red("[✘] Error: Could not serve the status on %s: %v", cfg.StatusSocket, err)
+			}
+		}()
+	}
+
+	if cfg.Proxies != nil {
+		if cfg.Proxies.Check() == 0 {
+			return fmt.Errorf("all %d proxies of -proxy/-proxy-file are down, none accepted a connection", cfg.Proxies.Len())
+		}
+		go cfg.Proxies.Run(ctx, cfg.ProxyCheckInterval, func() {
+			color.Red("\n[✘] Error: All %d proxies are down, stopping the scan", cfg.Proxies.Len())
+			atomic.StoreInt32(&s.aborted, 1)
+			cancel()
+		})
+	}
+
+	if cfg.MaxRuntime > 0 {
+		deadline := time.AfterFunc(cfg.MaxRuntime, func() {
+			color.Yellow("\n[!] Maximum runtime of %s reached, stopping the scan", cfg.MaxRuntime)
+			printResumeHint(cfg)
+			atomic.StoreInt32(&s.aborted, 1)
+			cancel()
+		})
+		defer deadline.Stop()
+	}
+
+	var domainChan <-chan string
+	if in.streamDomains {
+		// Domains are processed as soon as they arrive, e.g. when chained behind subfinder
+		streamed := make(chan string)
+		go domain.StreamDomains(os.Stdin, streamed, in.exclude, in.tags)
+		domainChan = streamed
+	} else {
+		// All domains are available right away, so the generator can interleave them from the start
+		domainChan = domainQueue(in.domains)
+	}
+	var hostPathSources []func(string) []string
+	if cfg.Wayback {
+		hostPathSources = append(hostPathSources, wayback.NewHarvester(cfg.WaybackLimit, cfg.WaybackCacheDir, cfg.Verbose).Paths)
+	}
+	if cfg.JSDiscovery {
+		hostPathSources = append(hostPathSources, endpoints.NewCrawler(limited, cfg.JSDepth, cfg.ForceHTTPProt, cfg.Verbose).Paths)
+	}
+	if cfg.OpenAPI {
+		hostPathSources = append(hostPathSources, openapi.NewExpander(limited, cfg.ForceHTTPProt, cfg.Verbose).Paths)
+	}
+	hostPaths := joinHostPaths(hostPathSources)
+	phases := in.phases(domainChan, hostPaths, cfg)
+
+	// With a Redis queue the generated URLs are published and the workers consume the shared queue
+	generatedURLs := urlChan
+	generatedCount := &totalURLs
+	if redisQueue != nil {
+		generatedURLs = make(chan string, cfg.URLBuffer)
+		// The local total says nothing about the shared queue
+		generatedCount = new(int64)
+		// Unbuffered, so URLs are only taken from the queue when a worker is ready for them
+		urlChan = make(chan string)
+
+		published := make(chan struct{})
+		go func() {
+			defer close(published)
+			if err := redisQueue.Publish(ctx, generatedURLs); err != nil {
+				// The generator stops once the context is cancelled instead of blocking on the full channel
+				color.Red("[✘] Error: Could not publish URLs to Redis, stopping the scan: %v", err)
+				atomic.StoreInt32(&s.aborted, 1)
+				cancel()
+			}
+		}()
+		go func() {
+			if err := redisQueue.Consume(ctx, urlChan, published); err != nil {
+				color.Red("[✘] Error: Could not consume URLs from Redis, stopping the scan: %v", err)
+				atomic.StoreInt32(&s.aborted, 1)
+				cancel()
+			}
+		}()
+	}
+
+	var pipeline *metrics.Pipeline
+	if cfg.Verbose {
+		// Periodically log queue depths and stall times to show where the scan is bottlenecked
+		pipeline = metrics.NewPipeline()
+		go pipeline.Report(ctx, pipelineReportInterval, cfg.Concurrency,
+			func() (int, int) { return len(generatedURLs), cap(generatedURLs) },
+			func() (int, int) { return len(resultsChan), cap(resultsChan) })
+	}
+
+	queuedURLs := generatedURLs
+	if cfg.SpillDir != "" {
+		// URLs the workers cannot take yet are buffered on disk instead of blocking the generator
+		spillQueue, err := spill.New(cfg.SpillDir)
+		if err != nil {
+			return fmt.Errorf("could not create the spill file: %w", err)
+		}
+		if cfg.Verbose {
+			log.Printf("Spilling queued URLs to %s\n", spillQueue.Path())
+		}
+		spillIn := make(chan string)
+		queuedURLs = spillIn
+		go func() {
+			if err := spillQueue.Run(ctx, spillIn, generatedURLs); err != nil {
+				color.Red("[✘] Error: %v", err)
+			}
+		}()
+	}
+
+	seen := newURLFilter(cfg)
+	var resume *resumeState
+	if cfg.ResumeFile != "" {
+		if seen == nil {
+			seen = bloom.New(resumeFilterCapacity, urlDedupFalsePositiveRate)
+		}
+		resume, err = openResumeState(cfg.ResumeFile, seen)
+		if err != nil {
+			return fmt.Errorf("could not open %s: %w", cfg.ResumeFile, err)
+		}
+		defer func() {
+			if err := resume.Close(); err != nil {
+				color.Red("[✘] Error: Could not write to %s: %v", cfg.ResumeFile, err)
+			}
+		}()
+		if resume.loaded > 0 {
+			color.Cyan("[i] Resuming the scan, skipping %d URLs recorded in %s", resume.loaded, cfg.ResumeFile)
+		}
+	}
+
+	if cfg.Randomize {
+		// Spread the load across hosts without materializing the full URL list
+		shuffleChan := make(chan string, cfg.URLBuffer)
+		go generateURLs(ctx, phases, cfg, seen, shuffleChan, generatedCount, &generating, pipeline, admission)
+		go utils.ShuffleWindow(shuffleChan, queuedURLs, cfg.RandomizeWindow)
+	} else {
+		go generateURLs(ctx, phases, cfg, seen, queuedURLs, generatedCount, &generating, pipeline, admission)
+	}
+
+	currentMarkers := func() []string { return in.markers }
+	if len(cfg.MarkersFiles) > 0 && cfg.MarkersReloadInterval > 0 {
+		watcher := markers.NewWatcher(cfg.MarkersFiles, in.markers, cfg.MarkersReloadInterval, func(lines []string) []string {
+			return result.PrepareMarkers(append(lines, cfg.Markers...), cfg)
+		}, cfg.Verbose)
+		stopWatching := make(chan struct{})
+		defer close(stopWatching)
+		go watcher.Watch(stopWatching)
+		currentMarkers = watcher.Markers
+	}
+
+	done := make(chan bool, 1)
+	if s.Progress {
+		go trackProgress(&processedCount, &totalURLs, &generating, done)
+	}
+
+	if cfg.Mode == config.ModeServe {
+		// Agents pull the generated URLs and send back their results instead of local workers
+		server := distributed.NewServer(cfg.ListenAddr, urlChan, resultsChan, cfg.Token, cfg.LeaseTimeout, &processedCount)
+		if err := server.Listen(); err != nil {
+			return fmt.Errorf("could not listen on %s: %w", cfg.ListenAddr, err)
+		}
+		go func() {
+			if err := server.Serve(); err != nil {
+				color.Red("[✘] Error: Agent API on %s failed: %v", cfg.ListenAddr, err)
+				cancel()
+			}
+		}()
+		defer server.Close()
+		color.Cyan("[i] Waiting for agents on %s", cfg.ListenAddr)
+
+		go func() {
+			<-server.Done()
+			close(resultsChan)
+			done <- true
+		}()
+	} else {
+		// Keyboard controls are only available if stdin is a terminal and not used for domains
+		var controller *control.Controller
+		if s.Controls && !in.streamDomains && isatty.IsTerminal(os.Stdin.Fd()) {
+			controller = control.NewController(limiter)
+			go controller.Listen(os.Stdin)
+			color.Cyan("[i] Controls: p + enter = pause, r + enter = resume, +/- + enter = adjust rate")
+		}
+
+		var autoscaler *control.Autoscaler
+		if cfg.Autoscale {
+			autoscaler = control.NewAutoscaler(cfg.Concurrency, cfg.MemoryLimit, cfg.Verbose)
+			go autoscaler.Run(ctx)
+		}
+
+		w := &workerContext{
+			client:         client,
+			calibrator:     calibrator,
+			rootDiffer:     rootDiffer,
+			favicons:       favicons,
+			shields:        shields,
+			cache:          cache,
+			rateGroups:     rateGroups,
+			wildcards:      wildcards,
+			matchTracker:   matchTracker,
+			processedCount: &processedCount,
+			limiter:        limiter,
+			controller:     controller,
+			autoscaler:     autoscaler,
+			redisQueue:     redisQueue,
+			pipeline:       pipeline,
+		}
+		if cfg.Verify {
+			w.verification = &verification{client: NewVerificationClient(cfg, overrides), delay: cfg.VerifyDelay, cfg: cfg, markers: currentMarkers, analyzers: analyzers}
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < cfg.Concurrency; i++ {
+			wg.Add(1)
+			go worker(ctx, urlChan, resultsChan, &wg, w)
+		}
+
+		go func() {
+			wg.Wait()
+			close(resultsChan)
+			done <- true
+		}()
+	}
+
+	findings := 0
+	hostFindings := make(map[string]int)
+	var transferred int64
+	unchanged := 0
+	for res := range resultsChan {
+		if cfg.MaxFindings > 0 && findings >= cfg.MaxFindings {
+			// Drain results of requests which were already in flight
+			continue
+		}
+
+		if res.Error == nil {
+			// Failed requests are tried again when the scan is resumed
+			if err := resume.Record(res.URL); err != nil {
+				color.Red("[✘] Error: Could not write to %s: %v", cfg.ResumeFile, err)
+			}
+		}
+		if cfg.MaxTotalBytes > 0 && transferred < cfg.MaxTotalBytes {
+			transferred += res.TransferSize
+			if transferred >= cfg.MaxTotalBytes {
+				color.Yellow("\n[!] Transfer budget of %d bytes used up, stopping the scan", cfg.MaxTotalBytes)
+				printResumeHint(cfg)
+				atomic.StoreInt32(&s.aborted, 1)
+				cancel()
+			}
+		}
+
+		status.Observe(res.URL, res.Error)
+		res.Tags = in.tags.Lookup(res.URL)
+		if storeAll != nil {
+			if err := storeAll.Write(res); err != nil {
+				color.Red("[✘] Error: Could not write to %s: %v", cfg.StoreAllFile, err)
+			}
+		}
+		if cfg.Monitor && res.Error == nil && (res.ETag != "" || res.LastModified != "") {
+			// The next iteration requests the URL conditionally
+			cfg.Conditions.Update(res.URL, request.Validators{ETag: res.ETag, LastModified: res.LastModified})
+		}
+		if res.Unchanged {
+			unchanged++
+			if s.OnUnchanged != nil {
+				s.OnUnchanged(res.URL)
+			}
+			continue
+		}
+		if matchTracker != nil && matchTracker.Muted(res.URL) {
+			continue
+		}
+		if wildcards.Observe(res) {
+			host := hosts.Host(res.URL)
+			responses, hits := wildcards.Counts(res.URL)
+			color.Yellow("\n[!] %s answered %d of %d requests with a 200, it is treated as wildcard responder and not scanned anymore. Its %d earlier findings are suspect",
+				host, hits, responses, hostFindings[host])
+			if s.OnWildcardHost != nil {
+				s.OnWildcardHost(host)
+			}
+		}
+		if wildcards.Flagged(res.URL) {
+			continue
+		}
+		finding, matched := result.ProcessResult(res, cfg, currentMarkers(), analyzers)
+		if !matched {
+			continue
+		}
+		hostFindings[hosts.Host(finding.URL)]++
+		status.RecordFinding(finding.URL)
+		onFinding(finding)
+		if s.Known == nil || !s.Known(finding.URL) {
+			onMatchExec.Run(ctx, finding)
+		}
+		if err := redisQueue.PublishFinding(finding); err != nil {
+			color.Red("[✘] Error: Could not publish finding to Redis: %v", err)
+		}
+		if matchTracker != nil {
+			matchTracker.RecordMatch(res.URL)
+		}
+
+		findings++
+		if cfg.MaxFindings > 0 && findings >= cfg.MaxFindings {
+			color.Yellow("\n[!] Reached %d findings, stopping the scan", findings)
+			cancel()
+		}
+	}
+
+	onMatchExec.Wait()
+
+	if hits, misses := cache.Stats(); hits > 0 {
+		color.Cyan("\n[i] Response cache: %d of %d URLs answered without a request", hits, hits+misses)
+	}
+	if cfg.Conditions != nil && !cfg.Monitor {
+		color.Cyan("\n[i] Conditional requests: %d of %d URLs known from %s unchanged", unchanged, cfg.Conditions.Len(), cfg.PreviousStoreFile)
+	}
+	if skipped := in.dnsWildcards.Skipped(); skipped > 0 {
+		color.Cyan("\n[i] DNS wildcard: %d hosts skipped", skipped)
+	}
+
+	if storeAll != nil {
+		if err := storeAll.Close(); err != nil {
+			return fmt.Errorf("could not write to %s: %w", cfg.StoreAllFile, err)
+		}
+	}
+
+	return nil
+}
+
+// Aborted reports whether the last Run stopped before all URLs were processed because ctx was
+// cancelled or -max-runtime or -max-total-bytes was reached. Reaching -max-findings does not count as aborted.
+func (s *Scanner) Aborted() bool {
+	return atomic.LoadInt32(&s.aborted) == 1
+}
+
+// Estimate runs the URL generation without sending requests and prints the expected scan size
+func (s *Scanner) Estimate() error {
+	cfg := s.cfg
+	in, err := s.loadInput()
+	if err != nil {
+		return err
+	}
+
+	if in.streamDomains {
+		streamed := make(chan string)
+		go domain.StreamDomains(os.Stdin, streamed, in.exclude, in.tags)
+		for d := range streamed {
+			in.domains = append(in.domains, d)
+		}
+		in.streamDomains = false
+	}
+
+	cloud, err := newCloudStorage(cfg)
+	if err != nil {
+		return err
+	}
+	in.cloud = cloud
+	if err := in.addChecks(cfg); err != nil {
+		return err
+	}
+
+	if err := validateInput(in, true); err != nil {
+		return err
+	}
+
+	type domainCount struct {
+		domain string
+		count  int
+	}
+
+	var counts []domainCount
+	var totalURLs, extraRequests int64
+
+	seen := newURLFilter(cfg)
+	for _, d := range in.domains {
+		var count int
+		if in.cloud != nil {
+			count = len(selectURLs(in.bucketURLs(cfg)(d), cfg, seen))
+		} else {
+			count = len(hostURLs(d, domainPathGroups(d, in.pathGroups(), in.pathsMap), cfg, seen))
+		}
+		counts = append(counts, domainCount{domain: d, count: count})
+		totalURLs += int64(count)
+
+		if cfg.Calibrate {
+			extraRequests += int64(cfg.CalibrationRequests)
+		}
+		if cfg.BaselineDiff {
+			extraRequests++
+		}
+	}
+
+	sort.Slice(counts, func(i, j int) bool { return counts[i].count > counts[j].count })
+
+	color.Cyan("[i] Estimated URLs per domain (top %d):", estimateTopDomains)
+	for i, c := range counts {
+		if i >= estimateTopDomains {
+			color.Cyan("  ... and %d more domains", len(counts)-estimateTopDomains)
+			break
+		}
+		color.Cyan("  %-60s %d", c.domain, c.count)
+	}
+
+	totalRequests := totalURLs + extraRequests
+	duration := time.Duration(float64(totalRequests) / float64(cfg.Concurrency) * float64(time.Second))
+
+	color.Cyan("\n[i] Domains: %d", len(counts))
+	color.Cyan("[i] Total URLs: %d", totalURLs)
+	if extraRequests > 0 {
+		color.Cyan("[i] Additional calibration/baseline requests: %d", extraRequests)
+	}
+	color.Cyan("[i] Projected duration at %d requests/s: %s", cfg.Concurrency, duration.Round(time.Second))
+
+	return nil
+}
+
+func validateInput(in input, detectSecrets bool) error {
+	if len(in.domains) == 0 && !in.streamDomains {
+		return fmt.Errorf("the domain list is empty, please provide at least one domain")
+	}
+
+	if in.cloud != nil {
+		// Bucket URLs need no paths and the provider signatures act as markers
+		return nil
+	}
+
+	if len(in.paths) == 0 && len(in.priorityPaths) == 0 && in.pathsMap.Len() == 0 {
+		return fmt.Errorf("the path list is empty, please provide at least one path")
+	}
+
+	if len(in.markers) == 0 && !detectSecrets && in.checks == nil {
+		color.Yellow("[!] Warning: The marker list is empty. The scan will just use the size filter which might not be very useful.")
+	}
+
+	return nil
+}
+
+func printInitialInfo(cfg config.Config, in input) {
+
+	switch {
+	case in.cloud != nil && in.streamDomains:
+		color.Cyan("[i] Scanning domains from stdin for %s buckets", strings.Join(cfg.CloudStorage, "/"))
+	case in.cloud != nil:
+		color.Cyan("[i] Scanning %d domains for %s buckets", len(in.domains), strings.Join(cfg.CloudStorage, "/"))
+	case in.streamDomains:
+		color.Cyan("[i] Scanning domains from stdin with %d paths", len(in.paths)+len(in.priorityPaths))
+	default:
+		color.Cyan("[i] Scanning %d domains with %d paths", len(in.domains), len(in.paths)+len(in.priorityPaths))
+	}
+	if len(in.priorityPaths) > 0 {
+		color.Cyan("[i] Requesting %d priority paths first", len(in.priorityPaths))
+	}
+	if in.pathsMap.Len() > 0 {
+		color.Cyan("[i] Using tailored paths for the hosts of %d patterns", in.pathsMap.Len())
+	}
+	color.Cyan("[i] Minimum file size to detect: %d bytes", cfg.MinContentSize)
+	color.Cyan("[i] Filtering for HTTP status code: %s", cfg.HTTPStatusCodes)
+
+	if cfg.Calibrate {
+		color.Cyan("[i] Calibrating soft-404 responses with %d random paths per host", cfg.CalibrationRequests)
+	}
+
+	if cfg.Shard != nil {
+		color.Cyan("[i] Scanning shard %s", cfg.Shard)
+	}
+
+	if cfg.BaselineDiff {
+		color.Cyan("[i] Reporting responses differing from the host root (similarity < %.2f)", cfg.BaselineSimilarity)
+	}
+
+	if len(cfg.ExtraHeaders) > 0 {
+		color.Cyan("[i] Using extra headers:")
+		for key, value := range cfg.ExtraHeaders {
+			color.Cyan("  %s: %s", key, value)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package scanner
+
+import (
+	"context"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+)
+
+// verification requests the matches of -verify a second time. It runs in the worker which made
+// the first request, so the verification waits for the same shields, rate groups and limiter as
+// the scan and the results consumer never waits for it. A nil *verification verifies nothing.
+type verification struct {
+	client    Client
+	delay     time.Duration
+	cfg       config.Config
+	markers   func() []string
+	analyzers result.Analyzers
+}
+
+// verify marks a matching res as unverified unless the match reproduces after the delay
+func (v *verification) verify(ctx context.Context, res *result.Result, w *workerContext) {
+	if v == nil || res.Error != nil || res.SoftNotFound || res.Blocked {
+		return
+	}
+	markers := v.markers()
+	if !result.Matches(*res, v.cfg, markers, v.analyzers) {
+		return
+	}
+
+	res.Unverified = true
+	timer := time.NewTimer(v.delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+	}
+	if w.shields.Wait(ctx, res.URL) != nil || w.rateGroups.Wait(ctx, res.URL) != nil || w.limiter.Wait(ctx) != nil {
+		return
+	}
+
+	w.pipeline.RequestStarted()
+	again := v.client.MakeRequest(res.URL)
+	w.pipeline.RequestFinished()
+	res.Unverified = !result.Reproduces(*res, again, v.cfg, markers, v.analyzers)
+}
@@ -0,0 +1,398 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/baseline"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/bloom"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/control"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/distributed"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/domain"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/fasthttp"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/favicon"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/hosts"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/http"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/metrics"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/request"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/waf"
+	"github.com/fatih/color"
+	"golang.org/x/time/rate"
+)
+
+const urlDedupFalsePositiveRate = 0.0001
+
+type Client interface {
+	MakeRequest(url string) result.Result
+}
+
+// NewClient returns the fasthttp or net/http client depending on cfg. bodyScanner, admission and
+// overrides may be nil.
+func NewClient(cfg config.Config, bodyScanner *result.BodyScanner, admission *control.Admission, overrides *request.Overrides) Client {
+	var timeouts *hosts.Timeouts
+	if cfg.AdaptiveTimeout {
+		timeouts = hosts.NewTimeouts(cfg.Timeout, cfg.AdaptiveTimeoutMax)
+	}
+
+	if cfg.FastHTTP {
+		client := fasthttp.NewClient(cfg)
+		client.SetBodyScanner(bodyScanner)
+		client.SetTimeouts(timeouts)
+		client.SetAdmission(admission)
+		client.SetOverrides(overrides)
+		return client
+	}
+	client := http.NewClient(cfg)
+	client.SetBodyScanner(bodyScanner)
+	client.SetTimeouts(timeouts)
+	client.SetAdmission(admission)
+	client.SetOverrides(overrides)
+	return client
+}
+
+// NewVerificationClient returns the client re-requesting matches for -verify. It reads the whole body
+// without a Range header and does not stop at the first marker.
+func NewVerificationClient(cfg config.Config, overrides *request.Overrides) Client {
+	if cfg.FastHTTP {
+		client := fasthttp.NewClient(cfg)
+		client.SetOverrides(overrides)
+		client.DisableRange()
+		return client
+	}
+	client := http.NewClient(cfg)
+	client.SetOverrides(overrides)
+	client.DisableRange()
+	return client
+}
+
+// urlPhase generates the URLs of its path groups for every domain received on domains. Within a
+// host the groups are requested in order, followed by the host specific paths of hostPaths.
+// pathsMap replaces the last group for the hosts it maps. If urls is set it replaces the path
+// based generation, e.g. for cloud storage buckets.
+type urlPhase struct {
+	domains    <-chan string
+	pathGroups [][]string
+	pathsMap   *domain.PathsMap
+	hostPaths  func(domain string) []string
+	urls       func(domain string) []string
+}
+
+// generateURLs sends the URLs of all phases which are not in seen to urlChan and closes it.
+// generating is set to zero once the total is final.
+func generateURLs(ctx context.Context, phases []urlPhase, cfg config.Config, seen *bloom.Filter, urlChan chan<- string, totalURLs *int64, generating *int32, pipeline *metrics.Pipeline, admission *control.Admission) {
+	defer close(urlChan)
+	defer atomic.StoreInt32(generating, 0)
+
+	for _, phase := range phases {
+		if !generatePhase(ctx, phase, cfg, seen, urlChan, totalURLs, pipeline, admission) {
+			return
+		}
+	}
+}
+
+// generatePhase sends the URLs of phase to urlChan and returns false if ctx was cancelled
+func generatePhase(ctx context.Context, phase urlPhase, cfg config.Config, seen *bloom.Filter, urlChan chan<- string, totalURLs *int64, pipeline *metrics.Pipeline, admission *control.Admission) bool {
+	// URLs of up to cfg.FairHosts hosts are interleaved, so every host gets its share of the workers
+	fairHosts := cfg.FairHosts
+	if fairHosts < 1 {
+		fairHosts = 1
+	}
+	queue := hosts.NewFairQueue()
+	domainsOpen := true
+	for {
+		for domainsOpen && queue.Hosts() < fairHosts {
+			if queue.Hosts() > 0 && admission.UnderPressure() {
+				// Work off the queued hosts before generating more URLs
+				break
+			}
+			admission.Wait(ctx)
+
+			// Don't hold back the queued hosts while waiting for streamed domains
+			d, received, open := receiveDomain(phase.domains, queue.Hosts() == 0)
+			domainsOpen = open
+			if !received {
+				break
+			}
+
+			var domainURLs []string
+			if phase.urls != nil {
+				domainURLs = selectURLs(phase.urls(d), cfg, seen)
+			} else {
+				pathGroups := domainPathGroups(d, phase.pathGroups, phase.pathsMap)
+				if phase.hostPaths != nil {
+					pathGroups = append(pathGroups[:len(pathGroups):len(pathGroups)], phase.hostPaths(d))
+				}
+				domainURLs = hostURLs(d, pathGroups, cfg, seen)
+			}
+			atomic.AddInt64(totalURLs, int64(len(domainURLs)))
+			queue.Add(domainURLs)
+		}
+
+		url, ok := queue.Next()
+		if !ok {
+			if !domainsOpen {
+				return true
+			}
+			continue
+		}
+
+		select {
+		case urlChan <- url:
+			continue
+		default:
+		}
+
+		// The queue is full, the workers cannot keep up
+		start := time.Now()
+		select {
+		case urlChan <- url:
+			pipeline.AddGeneratorStall(time.Since(start))
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// domainPathGroups returns pathGroups with the last group replaced by the tailored paths of d
+func domainPathGroups(d string, pathGroups [][]string, pathsMap *domain.PathsMap) [][]string {
+	mapped, ok := pathsMap.Paths(d)
+	if !ok || len(pathGroups) == 0 {
+		return pathGroups
+	}
+	last := len(pathGroups) - 1
+	return append(pathGroups[:last:last], mapped)
+}
+
+// hostURLs generates the URLs of all path groups for domain d, group after group
+func hostURLs(d string, pathGroups [][]string, cfg config.Config, seen *bloom.Filter) []string {
+	var urls []string
+	for _, paths := range pathGroups {
+		groupURLs, _ := domain.GenerateURLs([]string{d}, paths, &cfg)
+		urls = append(urls, selectURLs(groupURLs, cfg, seen)...)
+	}
+	return urls
+}
+
+// receiveDomain takes the next domain. Unless block is set it returns immediately if none is available.
+func receiveDomain(domains <-chan string, block bool) (d string, received, open bool) {
+	if block {
+		d, open = <-domains
+		return d, open, open
+	}
+
+	select {
+	case d, open = <-domains:
+		return d, open, open
+	default:
+		return "", false, true
+	}
+}
+
+// newURLFilter returns the bloom filter for duplicate URLs or nil if the deduplication is disabled
+func newURLFilter(cfg config.Config) *bloom.Filter {
+	if cfg.URLDedupCapacity == 0 {
+		return nil
+	}
+	return bloom.New(cfg.URLDedupCapacity, urlDedupFalsePositiveRate)
+}
+
+// selectURLs drops URLs of other shards and URLs which were already generated, e.g. because
+// different parts of a domain or different base paths resulted in the same URL
+func selectURLs(urls []string, cfg config.Config, seen *bloom.Filter) []string {
+	if cfg.Shard == nil && seen == nil {
+		return urls
+	}
+
+	selected := urls[:0]
+	for _, url := range urls {
+		if !cfg.Shard.Contains(url) {
+			continue
+		}
+		if seen != nil && !seen.AddIfNew(url) {
+			continue
+		}
+		selected = append(selected, url)
+	}
+	return selected
+}
+
+// workerContext bundles the components shared by all workers
+type workerContext struct {
+	client         Client
+	calibrator     *baseline.Calibrator
+	rootDiffer     *baseline.RootDiffer
+	favicons       *favicon.Fingerprinter
+	shields        *waf.Shields
+	cache          *result.ResponseCache
+	rateGroups     *hosts.RateGroups
+	wildcards      *hosts.WildcardDetector
+	matchTracker   *hosts.MatchTracker
+	processedCount *int64
+	limiter        *rate.Limiter
+	controller     *control.Controller
+	autoscaler     *control.Autoscaler
+	redisQueue     *distributed.RedisQueue
+	pipeline       *metrics.Pipeline
+	verification   *verification
+}
+
+func worker(ctx context.Context, urls <-chan string, results chan<- result.Result, wg *sync.WaitGroup, w *workerContext) {
+	defer wg.Done()
+
+	for {
+		url, ok := receiveURL(urls, w.pipeline)
+		if !ok {
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if w.matchTracker != nil && w.matchTracker.Stopped(url) {
+			atomic.AddInt64(w.processedCount, 1)
+			w.redisQueue.Ack(url)
+			continue
+		}
+
+		if w.shields.Skipped(url) || w.wildcards.Flagged(url) {
+			atomic.AddInt64(w.processedCount, 1)
+			w.redisQueue.Ack(url)
+			continue
+		}
+
+		if res, ok := w.cache.Get(url); ok {
+			atomic.AddInt64(w.processedCount, 1)
+			sendResult(results, res, w.pipeline)
+			w.redisQueue.Ack(url)
+			continue
+		}
+
+		w.controller.Wait(ctx)
+
+		// Shielded hosts are slowed down before a token of the global rate is taken
+		if err := w.shields.Wait(ctx, url); err != nil {
+			w.cache.Put(result.Result{URL: url, Error: err})
+			continue
+		}
+		if err := w.rateGroups.Wait(ctx, url); err != nil {
+			w.cache.Put(result.Result{URL: url, Error: err})
+			continue
+		}
+
+		err := w.limiter.Wait(ctx)
+		if err != nil {
+			w.cache.Put(result.Result{URL: url, Error: err})
+			continue
+		}
+		w.autoscaler.Acquire()
+		w.pipeline.RequestStarted()
+		res := w.client.MakeRequest(url)
+		w.pipeline.RequestFinished()
+		w.autoscaler.Release()
+		w.autoscaler.Observe(res)
+		atomic.AddInt64(w.processedCount, 1)
+
+		res.Blocked, res.WAF = w.shields.Observe(res)
+
+		if w.calibrator != nil && res.Error == nil {
+			res.SoftNotFound = w.calibrator.IsSoftNotFound(res)
+		}
+
+		if w.rootDiffer != nil && res.Error == nil && !res.SoftNotFound {
+			res.DiffersFromBaseline = w.rootDiffer.Differs(res)
+		}
+
+		if w.favicons != nil && res.Error == nil && !res.SoftNotFound {
+			res.FaviconHash = w.favicons.Hash(url)
+		}
+		w.verification.verify(ctx, &res, w)
+		w.cache.Put(res)
+
+		sendResult(results, res, w.pipeline)
+		w.redisQueue.Ack(url)
+	}
+}
+
+// receiveURL takes the next URL and records how long the worker waited for it
+func receiveURL(urls <-chan string, pipeline *metrics.Pipeline) (string, bool) {
+	select {
+	case url, ok := <-urls:
+		return url, ok
+	default:
+	}
+
+	start := time.Now()
+	url, ok := <-urls
+	pipeline.AddWorkerIdle(time.Since(start))
+	return url, ok
+}
+
+// sendResult hands res to the result processing and records how long the worker was blocked
+func sendResult(results chan<- result.Result, res result.Result, pipeline *metrics.Pipeline) {
+	select {
+	case results <- res:
+		return
+	default:
+	}
+
+	start := time.Now()
+	results <- res
+	pipeline.AddResultStall(time.Since(start))
+}
+
+// trackProgress prints the progress every second to the console output of color, which is stderr
+// with -output-format. The total grows while the URLs are generated,
+// so the ETA is only estimated once generating is zero.
+func trackProgress(processedCount, totalURLs *int64, generating *int32, done chan bool) {
+	start := time.Now()
+	lastProcessed := int64(0)
+	lastUpdate := start
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			now := time.Now()
+			elapsed := now.Sub(start)
+			currentProcessed := atomic.LoadInt64(processedCount)
+			total := atomic.LoadInt64(totalURLs)
+
+			// Calculate RPS
+			intervalElapsed := now.Sub(lastUpdate)
+			intervalProcessed := currentProcessed - lastProcessed
+			rps := float64(intervalProcessed) / intervalElapsed.Seconds()
+
+			if total > 0 && atomic.LoadInt32(generating) == 1 {
+				fmt.Fprintf(color.Output, "\r%-100s", "")
+				fmt.Fprintf(color.Output, "\rProgress: %d/%d+ | RPS: %.2f | Elapsed: %s | ETA: generating…",
+					currentProcessed, total, rps, elapsed.Round(time.Second))
+			} else if total > 0 {
+				percentage := float64(currentProcessed) / float64(total) * 100
+				eta := "-"
+				if currentProcessed > 0 {
+					estimatedTotal := float64(elapsed) / (float64(currentProcessed) / float64(total))
+					eta = time.Duration(estimatedTotal - float64(elapsed)).Round(time.Second).String()
+				}
+				fmt.Fprintf(color.Output, "\r%-100s", "")
+				fmt.Fprintf(color.Output, "\rProgress: %.2f%% (%d/%d) | RPS: %.2f | Elapsed: %s | ETA: %s",
+					percentage, currentProcessed, total, rps,
+					elapsed.Round(time.Second), eta)
+			} else {
+				fmt.Fprintf(color.Output, "\r%-100s", "")
+				fmt.Fprintf(color.Output, "\rProcessed: %d | RPS: %.2f | Elapsed: %s",
+					currentProcessed, rps, elapsed.Round(time.Second))
+			}
+
+			lastProcessed = currentProcessed
+			lastUpdate = now
+
+			time.Sleep(time.Second)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package shard
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Shard selects a deterministic subset of URLs, so a scan can be split across machines which
+// all generate the same URLs.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// Parse parses specs like 2/5 (the second of five shards, counting from 1)
+func Parse(spec string) (*Shard, error) {
+	parts := strings.SplitN(strings.TrimSpace(spec), "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid shard '%s', expected INDEX/COUNT (e.g. 2/5)", spec)
+	}
+
+	index, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard index '%s'", parts[0])
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid shard count '%s'", parts[1])
+	}
+	if index < 1 || index > count {
+		return nil, fmt.Errorf("shard index must be between 1 and %d", count)
+	}
+
+	return &Shard{Index: index, Count: count}, nil
+}
+
+// Contains reports whether url belongs to the shard. A nil shard contains every URL.
+func (s *Shard) Contains(url string) bool {
+	if s == nil {
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(url))
+	return int(h.Sum32()%uint32(s.Count)) == s.Index-1
+}
+
+func (s *Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
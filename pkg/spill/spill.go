@@ -0,0 +1,182 @@
+package spill
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Queue is a FIFO queue of strings between a producer and a consumer. Strings the consumer cannot
+// take right away are appended to a temporary file and read back sequentially, so the producer
+// can run ahead without holding its backlog in memory.
+type Queue struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	reader *bufio.Reader
+	readFd *os.File
+
+	// pending counts the strings in the file which were not read back yet, unflushed the
+	// ones of them still in the write buffer
+	pending   int64
+	unflushed int64
+}
+
+// New creates the spill file in dir (the system temp directory if empty)
+func New(dir string) (*Queue, error) {
+	file, err := os.CreateTemp(dir, "dfs-urls-*.spill")
+	if err != nil {
+		return nil, err
+	}
+
+	readFd, err := os.Open(file.Name())
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return &Queue{
+		path:   file.Name(),
+		file:   file,
+		writer: bufio.NewWriter(file),
+		reader: bufio.NewReader(readFd),
+		readFd: readFd,
+	}, nil
+}
+
+// Path returns the location of the spill file
+func (q *Queue) Path() string {
+	return q.path
+}
+
+// Run forwards all strings from in to out in order and closes out once in is drained. It returns
+// early if ctx is cancelled. The spill file is removed when Run returns.
+func (q *Queue) Run(ctx context.Context, in <-chan string, out chan<- string) error {
+	defer close(out)
+	defer q.remove()
+
+	var next string
+	hasNext := false
+	inOpen := true
+
+	for inOpen || hasNext {
+		var sendChan chan<- string
+		if hasNext {
+			sendChan = out
+		}
+		var receiveChan <-chan string
+		if inOpen {
+			receiveChan = in
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case s, ok := <-receiveChan:
+			if !ok {
+				inOpen = false
+				continue
+			}
+			if !hasNext {
+				next, hasNext = s, true
+				continue
+			}
+			if err := q.write(s); err != nil {
+				return q.passThrough(ctx, in, out, next, err)
+			}
+		case sendChan <- next:
+			hasNext = false
+			if q.pending == 0 {
+				continue
+			}
+			s, err := q.read()
+			if err != nil {
+				return q.passThrough(ctx, in, out, "", err)
+			}
+			next, hasNext = s, true
+		}
+	}
+
+	return nil
+}
+
+func (q *Queue) write(s string) error {
+	// Counted upfront, so a failed write is reported as lost
+	q.pending++
+	if _, err := q.writer.WriteString(s + "\n"); err != nil {
+		return err
+	}
+	q.unflushed++
+	return nil
+}
+
+func (q *Queue) read() (string, error) {
+	// Only flush once the reader caught up with the data written to the file
+	if q.pending == q.unflushed {
+		if err := q.writer.Flush(); err != nil {
+			return "", err
+		}
+		q.unflushed = 0
+	}
+
+	line, err := q.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	q.pending--
+
+	if q.pending == 0 {
+		// Everything was read back, start over to keep the file small
+		if err := q.reset(); err != nil {
+			return "", err
+		}
+	}
+
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+func (q *Queue) reset() error {
+	if err := q.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := q.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := q.readFd.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	q.writer.Reset(q.file)
+	q.reader.Reset(q.readFd)
+	return nil
+}
+
+// passThrough is the fallback after a spill file error: the remaining strings are forwarded
+// directly, the ones still in the file are lost
+func (q *Queue) passThrough(ctx context.Context, in <-chan string, out chan<- string, next string, cause error) error {
+	lost := q.pending
+	if next != "" {
+		select {
+		case out <- next:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	for s := range in {
+		select {
+		case out <- s:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return fmt.Errorf("spill file %s failed, %d URLs were lost: %w", q.path, lost, cause)
+}
+
+func (q *Queue) remove() {
+	q.file.Close()
+	q.readFd.Close()
+	os.Remove(q.path)
+}
@@ -0,0 +1,112 @@
+package statuscode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const maxStatusCode = 999
+
+// Matcher is a precomputed lookup table for HTTP status code filters.
+type Matcher struct {
+	allowed [maxStatusCode + 1]bool
+}
+
+// Parse compiles a csv status code filter. Supported forms are single codes (200), classes (2xx),
+// ranges (200-299) and negations of any of these (!404). If only negations are given, every other
+// status code is allowed.
+func Parse(spec string) (*Matcher, error) {
+	var include, exclude [][2]int
+
+	for _, term := range strings.Split(spec, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		negated := strings.HasPrefix(term, "!")
+		term = strings.TrimPrefix(term, "!")
+
+		from, to, err := parseTerm(term)
+		if err != nil {
+			return nil, err
+		}
+
+		if negated {
+			exclude = append(exclude, [2]int{from, to})
+		} else {
+			include = append(include, [2]int{from, to})
+		}
+	}
+
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, fmt.Errorf("empty status code filter")
+	}
+
+	if len(include) == 0 {
+		include = append(include, [2]int{0, maxStatusCode})
+	}
+
+	m := &Matcher{}
+	for _, r := range include {
+		for code := r[0]; code <= r[1]; code++ {
+			m.allowed[code] = true
+		}
+	}
+	for _, r := range exclude {
+		for code := r[0]; code <= r[1]; code++ {
+			m.allowed[code] = false
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Matcher) Matches(code int) bool {
+	if code < 0 || code > maxStatusCode {
+		return false
+	}
+	return m.allowed[code]
+}
+
+func parseTerm(term string) (int, int, error) {
+	lower := strings.ToLower(term)
+
+	if len(lower) == 3 && strings.HasSuffix(lower, "xx") {
+		class, err := strconv.Atoi(lower[:1])
+		if err != nil || class < 1 || class > 9 {
+			return 0, 0, fmt.Errorf("invalid status code class '%s'", term)
+		}
+		return class * 100, class*100 + 99, nil
+	}
+
+	if parts := strings.SplitN(term, "-", 2); len(parts) == 2 {
+		from, err := parseCode(parts[0])
+		if err != nil {
+			return 0, 0, err
+		}
+		to, err := parseCode(parts[1])
+		if err != nil {
+			return 0, 0, err
+		}
+		if from > to {
+			return 0, 0, fmt.Errorf("invalid status code range '%s'", term)
+		}
+		return from, to, nil
+	}
+
+	code, err := parseCode(term)
+	if err != nil {
+		return 0, 0, err
+	}
+	return code, code, nil
+}
+
+func parseCode(value string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || code < 0 || code > maxStatusCode {
+		return 0, fmt.Errorf("invalid status code '%s'", value)
+	}
+	return code, nil
+}
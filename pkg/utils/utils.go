@@ -2,15 +2,16 @@ package utils
 
 import (
 	"bufio"
-	"log"
+	"fmt"
 	"math/rand"
 	"os"
 )
 
-func ReadLines(filename string) []string {
+// ReadLines returns the lines of filename
+func ReadLines(filename string) ([]string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		log.Fatalf("Error opening file %s: %v\n", filename, err)
+		return nil, err
 	}
 	defer file.Close()
 
@@ -21,10 +22,24 @@ func ReadLines(filename string) []string {
 	}
 
 	if err := scanner.Err(); err != nil {
-		log.Fatalf("Error reading file %s: %v\n", filename, err)
+		return nil, fmt.Errorf("%s: %w", filename, err)
 	}
 
-	return lines
+	return lines, nil
+}
+
+// UniqueStrings removes duplicates while keeping the order of the first occurrences
+func UniqueStrings(slice []string) []string {
+	seen := make(map[string]struct{}, len(slice))
+	unique := slice[:0]
+	for _, s := range slice {
+		if _, exists := seen[s]; exists {
+			continue
+		}
+		seen[s] = struct{}{}
+		unique = append(unique, s)
+	}
+	return unique
 }
 
 func ShuffleStrings(slice []string) []string {
@@ -34,3 +49,29 @@ func ShuffleStrings(slice []string) []string {
 	}
 	return slice
 }
+
+// ShuffleWindow forwards all strings from in to out in random order, but only ever holds windowSize
+// strings in memory. Every incoming string replaces a randomly chosen one of the window which is emitted.
+// out is closed once in is drained.
+func ShuffleWindow(in <-chan string, out chan<- string, windowSize int) {
+	defer close(out)
+
+	if windowSize < 1 {
+		windowSize = 1
+	}
+
+	window := make([]string, 0, windowSize)
+	for s := range in {
+		if len(window) < windowSize {
+			window = append(window, s)
+			continue
+		}
+		i := rand.Intn(windowSize)
+		out <- window[i]
+		window[i] = s
+	}
+
+	for _, s := range ShuffleStrings(window) {
+		out <- s
+	}
+}
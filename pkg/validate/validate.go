@@ -0,0 +1,160 @@
+package validate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/config"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"github.com/fatih/color"
+)
+
+var hostRegex = regexp.MustCompile(`^([a-zA-Z0-9_]([a-zA-Z0-9_-]*[a-zA-Z0-9_])?\.)*[a-zA-Z0-9_]([a-zA-Z0-9_-]*[a-zA-Z0-9_])?(:\d{1,5})?$|^\[[0-9a-fA-F:]+\](:\d{1,5})?$`)
+
+type lineCheck func(line string) (skip bool, err error)
+
+// Run validates all input files referenced by cfg, prints malformed lines with their line numbers
+// and returns the number of problems found.
+func Run(cfg config.Config) int {
+	problems := 0
+
+	if cfg.DomainsFile == "-" {
+		color.Yellow("[!] domains are streamed from stdin and cannot be validated upfront")
+	} else if cfg.DomainsFile != "" {
+		problems += checkFile("domains", cfg.DomainsFile, checkDomain)
+	} else if cfg.Domain != "" {
+		if _, err := checkDomain(cfg.Domain); err != nil {
+			color.Red("[✘] -domain %s: %v", cfg.Domain, err)
+			problems++
+		} else {
+			color.Green("[✔] -domain: valid")
+		}
+	}
+
+	for _, pathsFile := range cfg.PathsFiles {
+		problems += checkFile("paths", pathsFile, checkPath)
+	}
+
+	for _, pathsFile := range cfg.PriorityPathsFiles {
+		problems += checkFile("priority paths", pathsFile, checkPath)
+	}
+
+	for _, markersFile := range cfg.MarkersFiles {
+		problems += checkFile("markers", markersFile, checkMarker)
+	}
+
+	for _, marker := range cfg.Markers {
+		if err := result.ValidateMarker(marker); err != nil {
+			color.Red("[✘] -marker %q: %v", marker, err)
+			problems++
+		}
+	}
+
+	if cfg.BasePathsFile != "" {
+		problems += checkFile("base paths", cfg.BasePathsFile, checkPath)
+	}
+
+	// Rules (status codes, regexes, sizes) were already validated while parsing the flags
+	color.Green("[✔] rules: valid")
+
+	if problems > 0 {
+		color.Red("\n[✘] Validation failed with %d problem(s)", problems)
+	} else {
+		color.Green("\n[✔] Validation passed")
+	}
+
+	return problems
+}
+
+func checkFile(kind, filename string, check lineCheck) int {
+	file, err := os.Open(filename)
+	if err != nil {
+		color.Red("[✘] %s file %s: %v", kind, filename, err)
+		return 1
+	}
+	defer file.Close()
+
+	valid, skipped, malformed := 0, 0, 0
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+
+		skip, err := check(line)
+		switch {
+		case err != nil:
+			color.Red("[✘] %s:%d: %v (%q)", filename, lineNumber, err, line)
+			malformed++
+		case skip:
+			skipped++
+		default:
+			valid++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		color.Red("[✘] %s file %s: %v", kind, filename, err)
+		malformed++
+	}
+
+	summary := fmt.Sprintf("%s file %s: %d valid, %d skipped, %d malformed", kind, filename, valid, skipped, malformed)
+	if malformed > 0 {
+		color.Red("[✘] %s", summary)
+	} else {
+		color.Green("[✔] %s", summary)
+	}
+
+	return malformed
+}
+
+func checkDomain(line string) (bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return true, nil
+	}
+
+	// Tags after a comma are not part of the host
+	host, _, _ := strings.Cut(line, ",")
+	host = strings.TrimSpace(host)
+	if idx := strings.Index(host, "://"); idx >= 0 {
+		scheme := strings.ToLower(host[:idx])
+		if scheme != "http" && scheme != "https" {
+			return false, fmt.Errorf("unsupported scheme '%s'", scheme)
+		}
+		host = host[idx+3:]
+	}
+	host = strings.SplitN(host, "/", 2)[0]
+
+	if !hostRegex.MatchString(host) {
+		return false, fmt.Errorf("invalid host '%s'", host)
+	}
+
+	return false, nil
+}
+
+func checkPath(line string) (bool, error) {
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "##") {
+		return true, nil
+	}
+
+	if strings.TrimSpace(line) != line {
+		return false, fmt.Errorf("leading or trailing whitespace")
+	}
+
+	for _, r := range line {
+		if r < 0x20 || r == 0x7f {
+			return false, fmt.Errorf("control character in path")
+		}
+	}
+
+	return false, nil
+}
+
+func checkMarker(line string) (bool, error) {
+	return false, result.ValidateMarker(line)
+}
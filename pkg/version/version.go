@@ -0,0 +1,38 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.:
+// go build -ldflags "-X github.com/dsecuredcom/dynamic-file-searcher/pkg/version.Version=1.2.0 -X github.com/dsecuredcom/dynamic-file-searcher/pkg/version.Commit=$(git rev-parse --short HEAD) -X github.com/dsecuredcom/dynamic-file-searcher/pkg/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// String returns a single line describing the binary. Without ldflags the VCS information
+// embedded by the Go toolchain is used as a fallback.
+func String() string {
+	commit, buildDate := Commit, BuildDate
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && commit == "unknown":
+				commit = setting.Value
+				if len(commit) > 12 {
+					commit = commit[:12]
+				}
+			case setting.Key == "vcs.time" && buildDate == "unknown":
+				buildDate = setting.Value
+			}
+		}
+	}
+
+	return fmt.Sprintf("dynamic_file_searcher %s (commit: %s, built: %s, %s %s/%s)",
+		Version, commit, buildDate, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
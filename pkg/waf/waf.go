@@ -0,0 +1,144 @@
+// Package waf recognizes the block and challenge pages of WAFs and CDNs and slows down the
+// requests to hosts shielded by them.
+package waf
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/hosts"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/result"
+	"github.com/fatih/color"
+	"golang.org/x/time/rate"
+)
+
+// A host is considered shielded after this many block pages, single blocks of sensitive paths
+// are common even without a WAF in front of the whole host
+const shieldThreshold = 3
+
+// signature recognizes a vendor by its server fingerprint and one of the texts of its block pages
+type signature struct {
+	vendor string
+	// server must be contained in the server fingerprint, empty matches every server
+	server string
+	texts  []string
+}
+
+var signatures = []signature{
+	{vendor: "Cloudflare", server: "cloudflare", texts: []string{"cf-error-details", "Attention Required! | Cloudflare", "Just a moment...", "challenge-platform", "cf-chl-"}},
+	{vendor: "Akamai", server: "akamaighost", texts: []string{"Access Denied", "Reference&#32;&#35;"}},
+	{vendor: "Akamai", texts: []string{"errors.edgesuite.net"}},
+	{vendor: "Imperva", texts: []string{"Incapsula incident ID", "_Incapsula_Resource"}},
+	{vendor: "AWS WAF", server: "awselb", texts: []string{"403 Forbidden"}},
+	{vendor: "AWS WAF", server: "cloudfront", texts: []string{"Request blocked", "The request could not be satisfied"}},
+	{vendor: "Sucuri", texts: []string{"Sucuri WebSite Firewall", "sucuri.net/privacy-policy"}},
+	{vendor: "F5 BIG-IP ASM", texts: []string{"The requested URL was rejected. Please consult with your administrator."}},
+	{vendor: "ModSecurity", texts: []string{"This error was generated by Mod_Security", "mod_security"}},
+}
+
+// Detect returns the vendor whose block or challenge page res is, empty if it is none
+func Detect(res result.Result) string {
+	if res.Error != nil || res.StatusCode < 400 {
+		return ""
+	}
+	for _, sig := range signatures {
+		if sig.server != "" && !strings.Contains(res.Server, sig.server) {
+			continue
+		}
+		for _, text := range sig.texts {
+			if strings.Contains(res.Content, text) {
+				return sig.vendor
+			}
+		}
+	}
+	return ""
+}
+
+type hostShield struct {
+	blocked int
+	vendor  string
+	limiter *rate.Limiter
+}
+
+// Shields counts the block pages per host. Once a host returned enough of them its requests are
+// limited to the shielded rate, a rate of 0 skips the remaining URLs of the host.
+type Shields struct {
+	rate float64
+
+	mu    sync.Mutex
+	hosts map[string]*hostShield
+}
+
+func NewShields(shieldedRate float64) *Shields {
+	return &Shields{
+		rate:  shieldedRate,
+		hosts: make(map[string]*hostShield),
+	}
+}
+
+// Observe records whether res is a block page and returns the vendor shielding its host, empty
+// if the host is not shielded (yet)
+func (s *Shields) Observe(res result.Result) (blocked bool, vendor string) {
+	if s == nil {
+		return false, ""
+	}
+	detected := Detect(res)
+	host := hosts.Host(res.URL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hs := s.hosts[host]
+	if detected != "" {
+		if hs == nil {
+			hs = &hostShield{}
+			s.hosts[host] = hs
+		}
+		hs.blocked++
+		hs.vendor = detected
+		if hs.blocked == shieldThreshold {
+			if s.rate > 0 {
+				hs.limiter = rate.NewLimiter(rate.Limit(s.rate), 1)
+				color.Yellow("\n[!] %s is shielded by %s, slowing down to %.2f requests/s", host, detected, s.rate)
+			} else {
+				color.Yellow("\n[!] %s is shielded by %s, skipping its remaining URLs", host, detected)
+			}
+		}
+	}
+
+	if hs == nil || hs.blocked < shieldThreshold {
+		return detected != "", ""
+	}
+	return detected != "", hs.vendor
+}
+
+// Skipped reports whether the host of rawURL is shielded and should not be requested anymore
+func (s *Shields) Skipped(rawURL string) bool {
+	if s == nil || s.rate > 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hs := s.hosts[hosts.Host(rawURL)]
+	return hs != nil && hs.blocked >= shieldThreshold
+}
+
+// Wait blocks until the shielded rate allows another request to the host of rawURL. It returns
+// immediately for hosts which are not shielded.
+func (s *Shields) Wait(ctx context.Context, rawURL string) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	var limiter *rate.Limiter
+	if hs := s.hosts[hosts.Host(rawURL)]; hs != nil {
+		limiter = hs.limiter
+	}
+	s.mu.Unlock()
+
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
@@ -0,0 +1,176 @@
+package wayback
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/domain"
+	"github.com/dsecuredcom/dynamic-file-searcher/pkg/utils"
+)
+
+const (
+	cdxURL         = "https://web.archive.org/cdx/search/cdx"
+	requestTimeout = 60 * time.Second
+	cacheMaxAge    = 7 * 24 * time.Hour
+	// The CDX API returns one line per archived URL, many of them collapse into the same path
+	cdxLinesPerPath = 5
+)
+
+// Static assets are archived a lot but never worth requesting again
+var ignoredExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".ico": true, ".webp": true,
+	".css": true, ".woff": true, ".woff2": true, ".ttf": true, ".eot": true, ".mp4": true, ".mp3": true,
+}
+
+// Harvester collects the paths the Wayback Machine observed for the apex domain of a host. Results
+// are cached per apex in memory and, if cacheDir is set, on disk.
+type Harvester struct {
+	client   *http.Client
+	limit    int
+	cacheDir string
+	verbose  bool
+
+	mu    sync.Mutex
+	cache map[string][]string
+}
+
+func NewHarvester(limit int, cacheDir string, verbose bool) *Harvester {
+	return &Harvester{
+		client:   &http.Client{Timeout: requestTimeout},
+		limit:    limit,
+		cacheDir: cacheDir,
+		verbose:  verbose,
+		cache:    make(map[string][]string),
+	}
+}
+
+// Paths returns up to limit archived paths and file names of the apex domain of host. Errors are
+// logged in verbose mode and result in no paths.
+func (h *Harvester) Paths(host string) []string {
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	apex := domain.Apex(strings.Split(host, "/")[0])
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if paths, ok := h.cache[apex]; ok {
+		return paths
+	}
+
+	paths, err := h.readCache(apex)
+	if err != nil {
+		paths, err = h.query(apex)
+		if err != nil {
+			if h.verbose {
+				log.Printf("Wayback lookup for %s failed: %v\n", apex, err)
+			}
+		} else {
+			h.writeCache(apex, paths)
+		}
+	}
+
+	if h.verbose && err == nil {
+		log.Printf("Wayback: %d paths for %s\n", len(paths), apex)
+	}
+
+	h.cache[apex] = paths
+	return paths
+}
+
+func (h *Harvester) query(apex string) ([]string, error) {
+	params := url.Values{}
+	params.Set("url", apex)
+	params.Set("matchType", "domain")
+	params.Set("fl", "original")
+	params.Set("collapse", "urlkey")
+	params.Set("filter", "statuscode:200")
+	params.Set("limit", fmt.Sprint(h.limit*cdxLinesPerPath))
+
+	resp, err := h.client.Get(cdxURL + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && len(paths) < h.limit {
+		for _, path := range archivedPaths(scanner.Text()) {
+			if !seen[path] && len(paths) < h.limit {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// archivedPaths returns the path and the file name of an archived URL, without the query
+func archivedPaths(rawURL string) []string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil
+	}
+
+	path := strings.Trim(parsed.Path, "/")
+	if path == "" || ignoredExtensions[strings.ToLower(filepath.Ext(path))] {
+		return nil
+	}
+
+	paths := []string{path}
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		paths = append(paths, path[i+1:])
+	}
+	return paths
+}
+
+func (h *Harvester) cacheFile(apex string) string {
+	return filepath.Join(h.cacheDir, apex+".txt")
+}
+
+func (h *Harvester) readCache(apex string) ([]string, error) {
+	if h.cacheDir == "" {
+		return nil, os.ErrNotExist
+	}
+
+	info, err := os.Stat(h.cacheFile(apex))
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(info.ModTime()) > cacheMaxAge {
+		return nil, os.ErrNotExist
+	}
+
+	return utils.ReadLines(h.cacheFile(apex))
+}
+
+func (h *Harvester) writeCache(apex string, paths []string) {
+	if h.cacheDir == "" {
+		return
+	}
+
+	err := os.MkdirAll(h.cacheDir, 0755)
+	if err == nil {
+		err = os.WriteFile(h.cacheFile(apex), []byte(strings.Join(paths, "\n")), 0644)
+	}
+	if err != nil && h.verbose {
+		log.Printf("Could not cache the wayback paths of %s: %v\n", apex, err)
+	}
+}